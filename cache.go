@@ -0,0 +1,185 @@
+package inflight
+
+import "time"
+
+// Peek returns the cached result of key, without executing any function nor
+// joining an in-flight call. The returned bool reports whether a successful
+// result is cached: it is false if key is absent, still in flight, or if its
+// cached result is an error or expired.
+//
+// Peek lets callers do opportunistic reads in a hot path, and fall back to
+// [Group.Do] on a miss.
+//
+// Peek is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Peek(key K) (V, bool) {
+	value, _, ok := g.PeekWithAge(key)
+	return value, ok
+}
+
+// PeekWithAge is like [Group.Peek], but also returns the age of the cached
+// result, that is how long ago its call completed. This lets callers refresh
+// a result before it expires, for instance once it reaches a fraction of the
+// TTL of the group.
+//
+// PeekWithAge is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) PeekWithAge(key K) (V, time.Duration, bool) {
+	now := g.opts.now()
+	call, ok := g.store().Load(key)
+	if !ok || !call.cached() || call.err != nil || call.expired(now) {
+		var zero V
+		return zero, 0, false
+	}
+	g.lru.touch(call)
+	return g.hooks.share(call.value), now.Sub(call.at), true
+}
+
+// CompletedAt returns the time the call whose result is cached for key
+// completed, as told by the clock of the group (see [WithClock]). The
+// returned bool is false if key is absent, still in flight, or if its cached
+// result expired. Unlike the age returned by [Group.PeekWithAge], the time is
+// absolute, so it can be compared with external events, such as the changes
+// of the source of the result.
+//
+// CompletedAt is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) CompletedAt(key K) (time.Time, bool) {
+	call, ok := g.store().Load(key)
+	if !ok || !call.completed() || call.expired(g.opts.now()) {
+		return time.Time{}, false
+	}
+	return call.at, true
+}
+
+// LastError returns the error of the completed call recorded for key, nil if
+// it succeeded, and whether such a call is recorded. Completed calls are only
+// recorded while their result is cached (see [WithTTL] and [WithErrorTTL]) or
+// kept (see [WithAutoForget]): the returned bool is false if key is absent,
+// still in flight, or if its result expired. This lets callers implement
+// circuit breaking, by opening the circuit of a key whose last call failed.
+//
+// LastError is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) LastError(key K) (error, bool) {
+	call, ok := g.store().Load(key)
+	if !ok || !call.completed() || call.expired(g.opts.now()) {
+		return nil, false
+	}
+	return call.err, true
+}
+
+// sweep removes the expired cached results of the group every interval, until
+// the group is closed, see [WithJanitor].
+func (g *Group[K, V]) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			now := g.opts.now()
+			for key, call := range g.store().Range {
+				if call.expired(now) && g.store().CompareAndDelete(key, call) {
+					g.evicted(key, call, Expired)
+				}
+			}
+		}
+	}
+}
+
+// Set stores value as the cached result of key, without executing any
+// function, so that the next calls for key are served from the cache. This
+// is useful to warm up a group, for instance from a snapshot at startup, see
+// [Group.Replace] for write-through caching. The value is cached for the TTL
+// of the group (see [WithTTL] and [WithTTLJitter]), or forever if it has
+// none.
+//
+// Set atomically replaces the entry of key. A cached result replaced is
+// reported to the eviction callback of the group (see [WithEvictionCallback])
+// with the reason [Replaced]. A call in flight for key keeps executing and
+// serving its existing waiters, but its result does not replace the value
+// set. Set does nothing once the group is closed, or if it is disabled (see
+// [WithDisabled]).
+//
+// Set is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Set(key K, value V) {
+	g.SetWithTTL(key, value, g.opts.jittered(g.opts.ttl))
+}
+
+// SetWithTTL is like [Group.Set], but caches value for d instead of the TTL
+// of the group. A value of d <= 0 caches it forever.
+//
+// SetWithTTL is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) SetWithTTL(key K, value V, d time.Duration) {
+	if g.closed.Load() || g.opts.disabled {
+		return
+	}
+	call := newCompletedCall(&g.opts, value, d)
+	if previous, ok := g.store().Swap(key, call); ok {
+		g.evicted(key, previous, Replaced)
+	}
+	g.cache(key, call)
+}
+
+// Replace atomically replaces the cached result of key with value, or stores
+// it if key has none, like [Group.Set]. It is meant for write-through caching:
+// a write path updates the cached result of a key right after writing it,
+// without waiting for the result to expire.
+//
+// The result replaced is reported to the eviction callback of the group (see
+// [WithEvictionCallback]) with the reason [Replaced], if it was a cached
+// value. A call in flight for key keeps serving its existing waiters, but its
+// result does not replace value.
+//
+// Replace is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Replace(key K, value V) {
+	g.SetWithTTL(key, value, g.opts.jittered(g.opts.ttl))
+}
+
+// GetOrSet returns the value of key if it has one, and stores value as its
+// cached result otherwise, like [Group.Set]: the first caller storing a value
+// wins, and the next callers get it. The returned bool reports whether value
+// was stored. This is handy for idempotent registrations, when the value is
+// already computed and no function needs to be executed.
+//
+// A call in flight for key is awaited: its value is returned if it succeeds,
+// and value is stored otherwise. Once the group is closed, or if it is
+// disabled (see [WithDisabled]), GetOrSet returns value and false without
+// storing it.
+//
+// GetOrSet is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) GetOrSet(key K, value V) (V, bool) {
+	if g.closed.Load() || g.opts.disabled {
+		return value, false
+	}
+	call := newCompletedCall(&g.opts, value, g.opts.jittered(g.opts.ttl))
+	for {
+		actual, loaded := g.loadOrStore(key, call)
+		if !loaded {
+			g.cache(key, call)
+			return value, true
+		}
+		<-actual.done
+		if actual.panic == nil && actual.err == nil {
+			g.lru.touch(actual)
+			return g.hooks.share(actual.value), false
+		}
+		// The call failed: it was evicted before publishing its error,
+		// unless the error is cached.
+		if g.store().CompareAndDelete(key, actual) {
+			g.evicted(key, actual, Replaced)
+		}
+	}
+}
+
+// newCompletedCall creates a completed call, whose result is value, cached
+// for d or forever if d <= 0.
+func newCompletedCall[V any](opts *options, value V, d time.Duration) *call[V] {
+	call := newCall[V](opts)
+	call.leave()
+	call.complete(value, nil, nil)
+	call.expires = noExpiry
+	if d > 0 {
+		call.expires = call.at.Add(d)
+	}
+	call.publish()
+	return call
+}
@@ -0,0 +1,379 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeek(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+
+	// Absent.
+	_, ok := g.Peek("key")
+	require.False(t, ok)
+
+	// In flight.
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "bar", nil
+		})
+	}()
+	<-started
+	_, ok = g.Peek("key")
+	require.False(t, ok)
+
+	// Cached.
+	close(block)
+	<-done
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithJanitor(t *testing.T) {
+	g := NewWithTTL[string, string](10*time.Millisecond, WithJanitor(5*time.Millisecond))
+	defer g.Close()
+
+	_, _, err := g.Do("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, 1, g.Len())
+
+	// The expired result is removed without accessing the key.
+	time.Sleep(50 * time.Millisecond)
+	require.Zero(t, g.Len())
+}
+
+func TestWithJanitorClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	g := NewWithTTL[string, string](time.Minute, WithJanitor(time.Millisecond))
+	require.Equal(t, before+1, runtime.NumGoroutine())
+
+	require.NoError(t, g.Close())
+	require.NoError(t, g.Close())
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		require.True(t, time.Now().Before(deadline), "janitor still running")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSet(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	g.Set("key", "bar")
+
+	v, shared, err := g.Do("key", func() (string, error) { panic("unreachable") })
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, "bar", v)
+
+	// A group without TTL keeps the value forever.
+	var g2 Group[string, string]
+	g2.Set("key", "bar")
+	v, ok := g2.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+}
+
+func TestSetWithTTL(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	g.SetWithTTL("key", "bar", 10*time.Millisecond)
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+
+	time.Sleep(20 * time.Millisecond)
+	v, _, err := g.Do("key", func() (string, error) { return "baz", nil })
+	require.NoError(t, err)
+	require.Equal(t, "baz", v)
+}
+
+func TestSetInFlight(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, _, err := g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "stale", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "stale", v)
+	}()
+	<-started
+
+	// The running leader is not canceled, but its result doesn't replace the
+	// value set.
+	g.Set("key", "bar")
+	close(block)
+	<-done
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithTTLJitter(t *testing.T) {
+	const (
+		n        = 200
+		ttl      = time.Hour
+		fraction = 0.5
+	)
+	g := New[int, int](WithTTL(ttl), WithTTLJitter(fraction))
+
+	start := time.Now()
+	for i := range n {
+		if i%2 == 0 {
+			_, _, err := g.Do(i, func() (int, error) { return i, nil })
+			require.NoError(t, err)
+		} else {
+			g.Set(i, i)
+		}
+	}
+	end := time.Now()
+
+	// Every expiry is within the jitter window, and they are spread across it.
+	window := time.Duration(fraction * float64(ttl))
+	earliest, latest := end.Add(ttl), start.Add(ttl)
+	for i := range n {
+		call, ok := g.store().Load(i)
+		require.True(t, ok)
+		require.False(t, call.expires.Before(start.Add(ttl-window)))
+		require.False(t, call.expires.After(end.Add(ttl+window)))
+		if call.expires.Before(earliest) {
+			earliest = call.expires
+		}
+		if call.expires.After(latest) {
+			latest = call.expires
+		}
+	}
+	require.True(t, earliest.Before(start.Add(ttl-window/2)))
+	require.True(t, latest.After(end.Add(ttl+window/2)))
+}
+
+func TestPeekWithAge(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+	g := NewWithTTL[string, string](ttl)
+
+	// Absent.
+	_, _, ok := g.PeekWithAge("key")
+	require.False(t, ok)
+
+	// In flight.
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (string, error) {
+			<-block
+			return "bar", nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the call is in flight.
+	_, _, ok = g.PeekWithAge("key")
+	require.False(t, ok)
+	close(block)
+	<-done
+
+	// Just after insertion.
+	v, age, ok := g.PeekWithAge("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+	require.Less(t, age, ttl/2)
+
+	// Near expiry.
+	time.Sleep(ttl * 3 / 4)
+	v, age, ok = g.PeekWithAge("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+	require.GreaterOrEqual(t, age, ttl*3/4)
+	require.Less(t, age, ttl)
+
+	// Expired.
+	time.Sleep(ttl - age)
+	_, _, ok = g.PeekWithAge("key")
+	require.False(t, ok)
+}
+
+func TestWithAdmission(t *testing.T) {
+	const maxLen = 3
+	g := NewWithTTL[string, string](time.Minute, WithAdmission(func(_ string, v string) bool {
+		return len(v) <= maxLen
+	}))
+
+	mustDo(t, g, "small", "foo")
+	mustDo(t, g, "large", "foobar")
+
+	v, ok := g.Peek("small")
+	require.True(t, ok)
+	require.Equal(t, "foo", v)
+
+	// A value which is not admitted is returned, but not cached.
+	_, ok = g.Peek("large")
+	require.False(t, ok)
+	require.Equal(t, 1, g.Len())
+	mustDo(t, g, "large", "barbaz")
+}
+
+func TestGetOrSet(t *testing.T) {
+	g := NewWithTTL[string, int](time.Minute)
+
+	const n = 16
+	var stored atomic.Int32
+	values := make(chan int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			v, ok := g.GetOrSet("key", i)
+			if ok {
+				stored.Add(1)
+				require.Equal(t, i, v)
+			}
+			values <- v
+		})
+	}
+	wg.Wait()
+	close(values)
+
+	// Exactly one value wins, and every caller gets it.
+	require.Equal(t, int32(1), stored.Load())
+	winner, ok := g.Peek("key")
+	require.True(t, ok)
+	for v := range values {
+		require.Equal(t, winner, v)
+	}
+}
+
+func TestGetOrSetInFlight(t *testing.T) {
+	var g Group[string, string]
+
+	// A successful call in flight wins.
+	block := make(chan struct{})
+	ch := g.DoChan("key", func() (string, error) {
+		<-block
+		return "foo", nil
+	})
+	require.Eventually(t, func() bool { return g.InFlight("key") }, time.Second, time.Millisecond)
+	time.AfterFunc(10*time.Millisecond, func() { close(block) })
+	v, ok := g.GetOrSet("key", "bar")
+	require.False(t, ok)
+	require.Equal(t, "foo", v)
+	require.Equal(t, "foo", (<-ch).Val)
+
+	// A failed one doesn't.
+	block = make(chan struct{})
+	ch = g.DoChan("key", func() (string, error) {
+		<-block
+		return "", errors.New("some error")
+	})
+	require.Eventually(t, func() bool { return g.InFlight("key") }, time.Second, time.Millisecond)
+	time.AfterFunc(10*time.Millisecond, func() { close(block) })
+	v, ok = g.GetOrSet("key", "bar")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+	require.Error(t, (<-ch).Err)
+	v, ok = g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithAutoForget(t *testing.T) {
+	const d = 50 * time.Millisecond
+	g := New[string, int](WithAutoForget(d))
+	var calls atomic.Int32
+	errFailed := errors.New("failed")
+	fn := func() (int, error) {
+		if n := calls.Add(1); n > 1 {
+			return int(n), nil
+		}
+		return 0, errFailed
+	}
+
+	start := time.Now()
+	_, shared, err := g.Do("key", fn)
+	require.ErrorIs(t, err, errFailed)
+	require.False(t, shared)
+
+	// The error is kept, without being cached.
+	_, shared, err = g.Do("key", fn)
+	require.ErrorIs(t, err, errFailed)
+	require.True(t, shared)
+	_, ok := g.Peek("key")
+	require.False(t, ok)
+	require.Equal(t, int32(1), calls.Load())
+
+	require.Eventually(t, func() bool { return g.Len() == 0 }, time.Second, time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(start), d)
+	v, shared, err := g.Do("key", fn)
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, 2, v)
+
+	// Context errors are never kept.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = g.DoContext(ctx, "canceled", func(context.Context) (int, error) { return 0, nil })
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []string{"key"}, slices.Collect(g.Keys()))
+}
+
+func TestReplace(t *testing.T) {
+	type eviction struct {
+		key, value string
+		reason     EvictReason
+	}
+	var evictions []eviction
+	g := New[string, string](WithTTL(time.Minute), WithEvictionCallback(func(key, value string, reason EvictReason) {
+		evictions = append(evictions, eviction{key, value, reason})
+	}))
+
+	g.Replace("key", "v1")
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "v1", v)
+	require.Empty(t, evictions)
+
+	g.Replace("key", "v2")
+	v, ok = g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "v2", v)
+	require.Equal(t, []eviction{{"key", "v1", Replaced}}, evictions)
+}
+
+func TestLastError(t *testing.T) {
+	g := New[string, string](WithTTL(time.Minute), WithErrorTTL(50*time.Millisecond))
+	_, ok := g.LastError("key")
+	require.False(t, ok)
+
+	errFailed := errors.New("failed")
+	g.Do("key", func() (string, error) { return "", errFailed })
+	err, ok := g.LastError("key")
+	require.True(t, ok)
+	require.ErrorIs(t, err, errFailed)
+
+	// Once the error expires, no call is recorded anymore.
+	require.Eventually(t, func() bool {
+		_, ok := g.LastError("key")
+		return !ok
+	}, time.Second, time.Millisecond)
+
+	g.Do("key", func() (string, error) { return "bar", nil })
+	err, ok = g.LastError("key")
+	require.True(t, ok)
+	require.NoError(t, err)
+}
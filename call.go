@@ -0,0 +1,408 @@
+package inflight
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// noExpiry is the expiration time of results cached forever, see
+// [Group.DoOnce].
+var noExpiry = time.Unix(1<<62, 0)
+
+// closedChan is a closed channel, used to report a vacant leadership.
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// call represents a single in-flight function execution.
+// It tracks the number of concurrent callers and publishes the result of the
+// function to all of them once it is executed by the leader.
+//
+// The leader of a call is the caller executing its function. A leader whose
+// context is canceled may step down, so that one of the waiters is promoted
+// to leader and executes its own function again, see [call.stepDown].
+type call[V any] struct {
+	callers    atomic.Int32 // number of callers currently executing or waiting on the call.
+	joins      atomic.Int32 // number of waiters which joined the call, see [call.orphaned].
+	departures atomic.Int32 // number of waiters which stopped waiting before the result, see [call.orphaned].
+	refreshing atomic.Bool  // whether a stale result is being refreshed, see [WithStaleWhileRevalidate].
+
+	opts    *options       // configuration of the group owning the call.
+	started time.Time      // creation time of the call, see [WithJoinPolicy].
+	merged  *mergedContext // see [WithMergedContext], nil if disabled.
+
+	mu        sync.Mutex
+	leading   bool          // whether a leader is executing the function.
+	finished  bool          // whether the result is set or being set.
+	abandoned bool          // whether the call was completed by its last waiter, see [call.abandon].
+	waiting   int           // number of waiters which can be promoted to leader.
+	ranks     map[int]int   // number of the waiters above per non-zero priority, see [Group.DoPriority].
+	ranked    int           // number of the waiters above with a non-zero priority.
+	vacant    chan struct{} // closed when the leader steps down.
+	lastErr   error         // error of the last leader who stepped down.
+	errs      []error       // errors of the leaders who stepped down, see [WithAggregateErrors].
+	memoize   bool          // whether a successful result is cached forever, see [Group.DoOnce].
+	stale     bool          // whether the result must not be cached, see [Group.Invalidate].
+	flushed   bool          // whether the call was completed by [Group.Flush].
+	kept      bool          // whether the result is kept once completed, see [WithAutoForget].
+	changes   int           // number of promotions of a waiter to leader, see [Info].
+	canceled  bool          // whether the call was canceled, see [Group.Cancel].
+	cancel    func()        // cancels the context of the function executing, if any.
+
+	done     chan struct{} // closed once the result below is set.
+	value    V
+	err      error
+	panic    any           // value recovered from a panicking function, if any.
+	expires  time.Time     // expiration time of a cached result, zero if not cached.
+	at       time.Time     // completion time of the call, see [Group.CompletedAt].
+	waiters  int32         // number of waiters besides the completing caller, see [Info].
+	elapsed  time.Duration // execution time of the function, see [Info].
+	deadline time.Time     // deadline of the context of the function, see [Info].
+	elem     *list.Element // element of the cached result in the lru of the group, see [WithMaxEntries].
+}
+
+// newCall creates a new [call], led by its creator who is already counted as
+// one of its callers. Its result is cached according to opts once it
+// completes.
+func newCall[V any](opts *options) *call[V] {
+	c := &call[V]{opts: opts, started: opts.now(), leading: true, done: make(chan struct{})}
+	if opts.mergedContext {
+		c.merged = newMergedContext(opts.cancelGrace)
+	}
+	c.callers.Store(1)
+	return c
+}
+
+// join registers a waiter on the call, and reports whether it succeeded.
+// It fails if the call already has maxWaiters waiters, with maxWaiters > 0.
+// A waiter with a function to execute is eligible for promotion if the
+// leader steps down, with the given priority.
+//
+// A successfully registered waiter must call [call.leave] once it stops
+// waiting.
+func (c *call[V]) join(eligible bool, priority, maxWaiters int) bool {
+	if n := c.callers.Add(1); maxWaiters > 0 && int(n)-1 > maxWaiters {
+		c.callers.Add(-1)
+		return false
+	}
+	c.joins.Add(1)
+	if eligible {
+		c.mu.Lock()
+		c.waiting++
+		if priority != 0 {
+			if c.ranks == nil {
+				c.ranks = make(map[int]int)
+			}
+			c.ranks[priority]++
+			c.ranked++
+		}
+		c.mu.Unlock()
+	}
+	return true
+}
+
+// unrank unregisters an eligible waiter with the given priority, which is
+// promoted or stops waiting. c.mu must be held.
+func (c *call[V]) unrank(priority int) {
+	c.waiting--
+	if priority == 0 {
+		return
+	}
+	c.ranked--
+	if c.ranks[priority]--; c.ranks[priority] == 0 {
+		delete(c.ranks, priority)
+	}
+}
+
+// top returns the highest priority of the eligible waiters. c.mu must be
+// held.
+func (c *call[V]) top() int {
+	top, ok := 0, c.waiting > c.ranked
+	for priority := range c.ranks {
+		if !ok || priority > top {
+			top, ok = priority, true
+		}
+	}
+	return top
+}
+
+// wake wakes up the waiters waiting for a vacancy, see [call.vacancy].
+// c.mu must be held.
+func (c *call[V]) wake() {
+	if c.vacant != nil {
+		close(c.vacant)
+		c.vacant = nil
+	}
+}
+
+// leave unregisters a caller from the call.
+func (c *call[V]) leave() { c.callers.Add(-1) }
+
+// setCancel registers cancel, which cancels the context of the function
+// executed by the leader, and calls it right away if the call was canceled.
+func (c *call[V]) setCancel(cancel func()) {
+	c.mu.Lock()
+	c.cancel = cancel
+	canceled := c.canceled
+	c.mu.Unlock()
+	if canceled {
+		cancel()
+	}
+}
+
+// abort cancels the context of the function of the call, and of the functions
+// executed by the next leaders, see [Group.Cancel]. It reports whether the
+// call was still in flight.
+func (c *call[V]) abort() bool {
+	c.mu.Lock()
+	if c.finished {
+		c.mu.Unlock()
+		return false
+	}
+	c.canceled = true
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return true
+}
+
+// orphaned reports whether the call is orphaned: all its waiters stopped
+// waiting before it completed, see [Stats]. A waiter promoted to leader
+// receives the result, so its call is not orphaned.
+func (c *call[V]) orphaned() bool {
+	joins := c.joins.Load()
+	return joins > 0 && c.departures.Load() == joins
+}
+
+// vacancy returns a channel closed once the leadership of the call is vacant
+// for a waiter with the given priority, that is once no eligible waiter has a
+// higher priority. The channel may also be closed earlier, when the vacancy
+// must be checked again.
+func (c *call[V]) vacancy(priority int) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.leading && !c.finished && priority >= c.top() {
+		return closedChan
+	}
+	if c.vacant == nil {
+		c.vacant = make(chan struct{})
+	}
+	return c.vacant
+}
+
+// stepDown is called by the leader once its function returned err. It reports
+// whether the leader stepped down instead of completing the call, which is
+// the case if its ctx is done, err is not nil, and waiters eligible for
+// promotion remain. The leader's cancellation is thus never propagated to
+// the waiters, as long as one of them can execute the function again.
+func (c *call[V]) stepDown(ctx context.Context, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil || ctx.Err() == nil || c.waiting == 0 || c.finished {
+		return false
+	}
+	c.leading = false
+	c.lastErr = err
+	if c.opts.aggregate {
+		c.errs = append(c.errs, err)
+	}
+	c.wake()
+	return true
+}
+
+// promote makes the calling waiter, with the given priority, the leader of
+// the call, and reports whether it succeeded. It fails if another waiter was
+// promoted first, if a waiter has a higher priority, or if the call
+// completed.
+func (c *call[V]) promote(priority int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.leading || c.finished || priority < c.top() {
+		return false
+	}
+	c.leading = true
+	c.unrank(priority)
+	c.changes++
+	return true
+}
+
+// abandon is called by an eligible waiter which stops waiting. It reports
+// whether the waiter must complete the call with the error of the last leader,
+// which is the case if it was the last waiter able to take over a vacant
+// leadership.
+func (c *call[V]) abandon(priority int) (lastErr error, last bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unrank(priority)
+	if c.waiting > 0 || c.leading || c.finished {
+		if !c.leading && !c.finished {
+			// The highest priority may have changed.
+			c.wake()
+		}
+		return nil, false
+	}
+	c.finished, c.abandoned = true, true
+	if len(c.errs) > 1 {
+		return errors.Join(c.errs...), true
+	}
+	return c.lastErr, true
+}
+
+// aggregate returns err, the error of the attempt completing the call, joined
+// to the errors of the previous attempts, see [WithAggregateErrors].
+func (c *call[V]) aggregate(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil || len(c.errs) == 0 {
+		return err
+	}
+	return errors.Join(append(c.errs[:len(c.errs):len(c.errs)], err)...)
+}
+
+// complete sets the result of the call, and reports whether it is cached.
+// A non-nil p is the value recovered from a panicking function, re-panicked
+// by every waiter. ok is false if the call was flushed, see [call.flush], in
+// which case the result is discarded and must not be published.
+//
+// The result is only published to the waiters once [call.publish] is called,
+// which allows evicting the call before its result is visible.
+func (c *call[V]) complete(value V, err error, p any) (cached, ok bool) {
+	c.mu.Lock()
+	if c.flushed {
+		c.mu.Unlock()
+		return false, false
+	}
+	c.leading, c.finished = false, true
+	memoize, stale := c.memoize, c.stale
+	c.mu.Unlock()
+	c.value, c.err, c.panic = value, err, p
+	c.waiters = max(c.callers.Load()-1, 0)
+	c.at = c.opts.now()
+	switch ttl := c.opts.cacheTTL(err); {
+	case p != nil, stale:
+	case memoize && err == nil:
+		c.expires = noExpiry
+	case ttl > 0:
+		c.expires = c.at.Add(ttl)
+	}
+	c.kept = c.expires.IsZero() && c.opts.autoForget > 0 && p == nil && !stale && !transient(err)
+	return !c.expires.IsZero(), true
+}
+
+// flush completes the call with [ErrFlushed] and publishes it, discarding
+// the result of its function, and reports whether it succeeded. It fails if
+// the call is already completing.
+func (c *call[V]) flush() bool {
+	c.mu.Lock()
+	if c.finished {
+		c.mu.Unlock()
+		return false
+	}
+	c.leading, c.finished, c.flushed = false, true, true
+	c.mu.Unlock()
+	c.err = ErrFlushed
+	c.waiters = max(c.callers.Load()-1, 0)
+	c.publish()
+	return true
+}
+
+// publish publishes the result set by [call.complete] to the waiters.
+func (c *call[V]) publish() { close(c.done) }
+
+// invalidate marks the call as stale, so its result is not cached once it
+// completes.
+func (c *call[V]) invalidate() {
+	c.mu.Lock()
+	c.stale = true
+	c.mu.Unlock()
+}
+
+// setMemoize caches the successful result of the call forever.
+func (c *call[V]) setMemoize() {
+	c.mu.Lock()
+	c.memoize = true
+	c.mu.Unlock()
+}
+
+// result returns the result of a completed call, propagating the panic of
+// its function, if any.
+func (c *call[V]) result() (V, error) {
+	if c.panic != nil {
+		panic(c.panic)
+	}
+	return c.value, c.err
+}
+
+// completed reports whether the result of the call is published.
+func (c *call[V]) completed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// info returns the [Info] of a caller which got the result of the completed
+// call without executing its function.
+func (c *call[V]) info() Info {
+	return Info{Shared: true, Waiters: c.waiters, Duration: c.elapsed, LeaderChanges: c.changes, Deadline: c.deadline}
+}
+
+// failed reports whether the completed call returned an error which is not
+// cached, see [WithErrorFailover].
+func (c *call[V]) failed() bool {
+	return c.err != nil && c.err != ErrFlushed && c.panic == nil && c.expires.IsZero()
+}
+
+// cached reports whether the call completed with a result that is cached.
+func (c *call[V]) cached() bool {
+	return c.completed() && !c.expires.IsZero()
+}
+
+// expired reports whether the call has a cached result which expired at now.
+func (c *call[V]) expired(now time.Time) bool {
+	return c.cached() && !now.Before(c.expires)
+}
+
+// execute calls fn with ctx, then calls then with its results, even if fn
+// panics or calls [runtime.Goexit], in which case err is errGoexit.
+//
+// If fn panics, p is the recovered value, which is propagated once then
+// returns, unless opts has panicToError set: the panic is then converted to
+// a [*PanicError] returned as err.
+func execute[V any](ctx context.Context, opts *options, fn func(context.Context) (V, error), then func(value V, err error, p any)) {
+	var (
+		value    V
+		err      error
+		p        any
+		returned bool
+	)
+	defer func() {
+		if !returned {
+			switch r := recover(); {
+			case r == nil:
+				err = errGoexit
+			case opts.panicToError:
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			default:
+				p = r
+			}
+		}
+		then(value, err, p)
+		if p != nil {
+			panic(p)
+		}
+	}()
+	value, err = fn(ctx)
+	returned = true
+}
@@ -0,0 +1,63 @@
+package inflight
+
+import (
+	"context"
+	"time"
+)
+
+// CallOption configures a single call made with [Group.DoOpts].
+type CallOption func(*callOptions)
+
+// SkipCache makes the call ignore the cached result of its key, if any: the
+// caller executes its function as the leader of a new call, whose result
+// replaces the cached one. A call in flight is still joined, since its result
+// is fresh.
+func SkipCache() CallOption {
+	return func(co *callOptions) { co.skipCache = true }
+}
+
+// NoJoin makes the call never join the call in flight for its key: the caller
+// executes its function as the leader of a new call, which replaces the call
+// in flight for the next callers of the key, like with [WithJoinPolicy]. The
+// replaced call keeps executing and serving its existing waiters. A cached
+// result is still used, see [SkipCache].
+func NoJoin() CallOption {
+	return func(co *callOptions) { co.noJoin = true }
+}
+
+// WaitTimeout makes the caller stop waiting for the result of the call once d
+// elapses, as if it called [Group.DoContext] with a context expiring after d:
+// it then gets [context.DeadlineExceeded]. A caller executing its function
+// still returns once the function returns, since it takes no context. Unlike
+// [WithCallTimeout], it only bounds the wait of this caller, never the
+// execution of the function, which keeps serving the other callers. A value
+// of d <= 0 means no timeout, which is the default.
+func WaitTimeout(d time.Duration) CallOption {
+	return func(co *callOptions) { co.timeout = d }
+}
+
+// DoOpts is like [Group.Do], but configured with opts for this call only,
+// instead of for the whole group: see [SkipCache], [NoJoin] and
+// [WaitTimeout]. Without options, it behaves exactly like [Group.Do].
+//
+// All the options are compatible with each other, and with the options of
+// the group. SkipCache and NoJoin combined always execute fn, like
+// [Group.DoFresh], except that the callers arriving while fn executes join
+// its call.
+//
+// DoOpts is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoOpts(key K, fn func() (V, error), opts ...CallOption) (V, bool, error) {
+	var co callOptions
+	for _, opt := range opts {
+		opt(&co)
+	}
+	ctx := context.Background()
+	if co.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, co.timeout)
+		defer cancel()
+	}
+	co.ctxless = true
+	value, info, err := g.do(ctx, key, func(context.Context) (V, error) { return fn() }, co)
+	return value, info.Shared, err
+}
@@ -0,0 +1,97 @@
+package inflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoOpts(t *testing.T) {
+	t.Run("no_options", func(t *testing.T) {
+		g := NewWithTTL[string, string](time.Minute)
+		g.Set("key", "cached")
+		v, shared, err := g.DoOpts("key", func() (string, error) { return "fresh", nil })
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "cached", v)
+	})
+
+	t.Run("skip_cache", func(t *testing.T) {
+		var evicted []EvictReason
+		g := New[string, string](WithTTL(time.Minute), WithEvictionCallback(func(_ string, _ string, reason EvictReason) {
+			evicted = append(evicted, reason)
+		}))
+		var calls atomic.Int32
+		fn := func() (string, error) {
+			return fmt.Sprintf("value %d", calls.Add(1)), nil
+		}
+		v, _, err := g.Do("key", fn)
+		require.NoError(t, err)
+		require.Equal(t, "value 1", v)
+
+		v, shared, err := g.DoOpts("key", fn, SkipCache())
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, "value 2", v)
+		require.Equal(t, []EvictReason{Replaced}, evicted)
+
+		// The fresh result replaced the cached one.
+		v, shared, err = g.Do("key", fn)
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "value 2", v)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("no_join", func(t *testing.T) {
+		var g Group[string, string]
+		block := make(chan struct{})
+		var started, wg sync.WaitGroup
+		started.Add(1)
+		wg.Go(func() {
+			v, _, err := g.Do("key", func() (string, error) {
+				started.Done()
+				<-block
+				return "slow", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "slow", v)
+		})
+		started.Wait()
+
+		v, shared, err := g.DoOpts("key", func() (string, error) { return "fast", nil }, NoJoin())
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, "fast", v)
+
+		close(block)
+		wg.Wait()
+	})
+
+	t.Run("wait_timeout", func(t *testing.T) {
+		var g Group[string, string]
+		block := make(chan struct{})
+		var started, wg sync.WaitGroup
+		started.Add(1)
+		wg.Go(func() {
+			g.Do("key", func() (string, error) {
+				started.Done()
+				<-block
+				return "slow", nil
+			})
+		})
+		started.Wait()
+
+		_, shared, err := g.DoOpts("key", func() (string, error) { return "", nil }, WaitTimeout(10*time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.True(t, shared)
+
+		close(block)
+		wg.Wait()
+	})
+}
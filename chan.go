@@ -0,0 +1,75 @@
+package inflight
+
+import "context"
+
+// Result holds the results of [Group.DoChan], so they can be passed on a
+// channel.
+type Result[V any] struct {
+	Val    V     // value returned by the function.
+	Err    error // error returned by the function.
+	Shared bool  // whether the result was shared with other callers.
+	// Waiters is the number of callers which were waiting on the call when
+	// it completed, see [Info].
+	Waiters int32
+}
+
+// DoChan is like [Group.Do] but returns a channel that will receive the
+// result when it is ready, so callers can select on it alongside a context
+// or a timer.
+//
+// The returned channel is buffered with a capacity of 1, delivering the
+// result never blocks even if the caller stops listening on it: an unread
+// channel is simply garbage collected. It is closed after the result is sent.
+// See [WithChanTimeout] to bound the wait for the result. Every caller joining
+// the same in-flight call receives its own copy of the result on its own
+// channel.
+//
+// DoChan is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	return g.doChan(context.Background(), key, func(context.Context) (V, error) { return fn() })
+}
+
+// DoChanContext is like [Group.DoChan], with the cancellation semantics of
+// [Group.DoContext]: once ctx is done, the returned channel receives a
+// [Result] whose Err is ctx.Err(), without affecting the other callers of the
+// in-flight call. A result already published when the caller notices its ctx
+// is done takes precedence over the cancellation: the channel receives the
+// result instead. With [WithMergedContext], fn gets a context canceled only
+// once the contexts of all the callers are done, so that the cancellation of
+// some callers never interrupts the function serving the others.
+//
+// The returned channel is buffered with a capacity of 1, and is closed after
+// the result is sent. Delivering the result never blocks: the goroutine
+// waiting on the call for the caller returns once the result is delivered or
+// ctx is done, even if the channel is abandoned.
+//
+// DoChanContext is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoChanContext(ctx context.Context, key K, fn func(context.Context) (V, error)) <-chan Result[V] {
+	return g.doChan(ctx, key, fn)
+}
+
+// doChan implements [Group.DoChan] and [Group.DoChanContext].
+func (g *Group[K, V]) doChan(ctx context.Context, key K, fn func(context.Context) (V, error)) <-chan Result[V] {
+	ch := make(chan Result[V], 1)
+	if g.closed.Load() {
+		g.stats.calls.Add(1)
+		ch <- Result[V]{Err: ErrClosed}
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer close(ch)
+		parent := ctx
+		if d := g.opts.chanTimeout; d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		value, info, err := g.do(ctx, key, fn, callOptions{})
+		if err != nil && err == ctx.Err() && parent.Err() == nil {
+			g.hooks.onDrop(key)
+		}
+		ch <- Result[V]{Val: value, Err: err, Shared: info.Shared, Waiters: info.Waiters}
+	}()
+	return ch
+}
@@ -0,0 +1,171 @@
+package inflight
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoChanContext(t *testing.T) {
+	var g Group[string, string]
+
+	block := make(chan struct{})
+	fn := func(context.Context) (string, error) {
+		<-block
+		return "bar", nil
+	}
+
+	leader := g.DoChanContext(t.Context(), "key", fn)
+	time.Sleep(10 * time.Millisecond) // Ensure the first call is in flight.
+
+	ctx, cancel := context.WithCancel(t.Context())
+	canceled := g.DoChanContext(ctx, "key", fn)
+	waiter := g.DoChanContext(t.Context(), "key", fn)
+	time.Sleep(10 * time.Millisecond) // Ensure the waiters joined.
+
+	cancel()
+	res := <-canceled
+	require.ErrorIs(t, res.Err, context.Canceled)
+	require.True(t, res.Shared)
+	require.Empty(t, res.Val)
+	_, ok := <-canceled
+	require.False(t, ok, "channel must be closed after one send")
+
+	close(block)
+	for _, ch := range []<-chan Result[string]{leader, waiter} {
+		res := <-ch
+		require.NoError(t, res.Err)
+		require.Equal(t, "bar", res.Val)
+		require.True(t, res.Shared)
+		_, ok := <-ch
+		require.False(t, ok, "channel must be closed after one send")
+	}
+}
+
+func TestWithChanTimeout(t *testing.T) {
+	var o recordingObserver[string, string]
+	g := New[string, string](WithChanTimeout(20*time.Millisecond), WithObserver[string, string](&o))
+
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (string, error) {
+			<-block
+			return "bar", nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the first call is in flight.
+
+	before := runtime.NumGoroutine()
+	for range 8 {
+		// The channels are abandoned without being read.
+		g.DoChan("key", func() (string, error) { panic("unreachable") })
+	}
+
+	// The goroutines delivering the results exit once the timeout elapses,
+	// even though the call is still in flight.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		require.True(t, time.Now().Before(deadline), "goroutine leak")
+		time.Sleep(5 * time.Millisecond)
+	}
+	o.mu.Lock()
+	require.Equal(t, slices.Repeat([]string{"key"}, 8), o.drops)
+	o.mu.Unlock()
+
+	ch := g.DoChan("key", func() (string, error) { panic("unreachable") })
+	res := <-ch
+	require.ErrorIs(t, res.Err, context.DeadlineExceeded)
+	require.True(t, res.Shared)
+
+	close(block)
+	<-done
+	res = <-g.DoChan("key", func() (string, error) { return "baz", nil })
+	require.NoError(t, res.Err)
+	require.Equal(t, "baz", res.Val)
+}
+
+func TestDoChanWaiters(t *testing.T) {
+	var g Group[string, string]
+
+	const n = 8
+	block := make(chan struct{})
+	chans := make([]<-chan Result[string], n)
+	for i := range chans {
+		chans[i] = g.DoChan("key", func() (string, error) {
+			<-block
+			return "bar", nil
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+
+	for _, ch := range chans {
+		res := <-ch
+		require.NoError(t, res.Err)
+		require.Equal(t, "bar", res.Val)
+		require.GreaterOrEqual(t, res.Waiters, int32(n-1))
+	}
+}
+
+func TestDoChanContextMerged(t *testing.T) {
+	g := New[string, string](WithMergedContext())
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-block:
+			return "bar", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	before := runtime.NumGoroutine()
+	leaderCtx, cancelLeader := context.WithCancel(t.Context())
+	leader := g.DoChanContext(leaderCtx, "key", fn)
+	<-started
+
+	const n = 4
+	ctx, cancel := context.WithCancel(t.Context())
+	canceled := make([]<-chan Result[string], n)
+	completed := make([]<-chan Result[string], n)
+	for i := range n {
+		canceled[i] = g.DoChanContext(ctx, "key", fn)
+		completed[i] = g.DoChanContext(t.Context(), "key", fn)
+		// An abandoned channel is never read.
+		g.DoChanContext(ctx, "key", fn)
+	}
+	require.Eventually(t, func() bool { return g.Waiters("key") == 3*n+1 }, time.Second, time.Millisecond)
+
+	// The cancellations of the leader and some waiters do not interrupt fn.
+	cancelLeader()
+	cancel()
+	for _, ch := range canceled {
+		res := <-ch
+		require.ErrorIs(t, res.Err, context.Canceled)
+		require.True(t, res.Shared)
+	}
+	require.True(t, g.InFlight("key"))
+
+	close(block)
+	// The leader returns once fn returns, its result wins.
+	for _, ch := range append(completed, leader) {
+		res := <-ch
+		require.NoError(t, res.Err)
+		require.Equal(t, "bar", res.Val)
+		require.True(t, res.Shared)
+	}
+
+	// No goroutine is left behind by the abandoned channels.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		require.True(t, time.Now().Before(deadline), "goroutine leak")
+		time.Sleep(5 * time.Millisecond)
+	}
+}
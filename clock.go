@@ -0,0 +1,30 @@
+package inflight
+
+import "time"
+
+// Clock tells the current time to a [Group], to compute the expiration of its
+// cached results and the age of its calls, see [WithClock].
+//
+// A Clock must be safe for concurrent use by multiple goroutines.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock makes the group tell the time with c instead of [time.Now], so
+// that tests can use a fake clock to expire cached results (see [WithTTL])
+// deterministically, without sleeping. The janitor (see [WithJanitor]) still
+// ticks in real time, but expires results according to c.
+//
+// The execution times of the functions, such as [Info.Duration], are always
+// measured in real time.
+func WithClock(c Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// now returns the current time according to the clock of the group.
+func (o *options) now() time.Time {
+	if o.clock == nil {
+		return time.Now()
+	}
+	return o.clock.Now()
+}
@@ -0,0 +1,106 @@
+package inflight
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a [Clock] whose time only moves when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock { return &fakeClock{now: time.Unix(0, 0)} }
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWithClock(t *testing.T) {
+	clock := newFakeClock()
+	g := NewWithTTL[string, string](time.Hour, WithClock(clock))
+	mustDo(t, g, "key", "foo")
+
+	clock.Advance(time.Hour - time.Nanosecond)
+	v, age, ok := g.PeekWithAge("key")
+	require.True(t, ok)
+	require.Equal(t, "foo", v)
+	require.Equal(t, time.Hour-time.Nanosecond, age)
+	v, shared, err := g.Do("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, "foo", v)
+
+	// The result expires once the clock reaches its TTL.
+	clock.Advance(time.Nanosecond)
+	_, ok = g.Peek("key")
+	require.False(t, ok)
+	mustDo(t, g, "key", "bar")
+}
+
+func TestWithClockJanitor(t *testing.T) {
+	clock := newFakeClock()
+	g := NewWithTTL[string, string](time.Hour, WithClock(clock), WithJanitor(time.Millisecond))
+	defer g.Close()
+	mustDo(t, g, "key", "foo")
+
+	// The janitor ticks without expiring the result.
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, 1, g.Len())
+
+	// Until the clock reaches its TTL.
+	clock.Advance(time.Hour)
+	require.Eventually(t, func() bool { return g.Len() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestCompletedAt(t *testing.T) {
+	clock := newFakeClock()
+	g := NewWithTTL[string, string](time.Hour, WithClock(clock))
+	_, ok := g.CompletedAt("key")
+	require.False(t, ok)
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "foo", nil
+		})
+	}()
+	<-started
+	_, ok = g.CompletedAt("key")
+	require.False(t, ok, "in flight")
+
+	clock.Advance(time.Minute)
+	completion := clock.Now()
+	close(block)
+	<-done
+	at, ok := g.CompletedAt("key")
+	require.True(t, ok)
+	require.Equal(t, completion, at)
+
+	// The time is stable, until the result expires.
+	clock.Advance(time.Hour - time.Nanosecond)
+	mustDo(t, g, "key", "foo")
+	at, ok = g.CompletedAt("key")
+	require.True(t, ok)
+	require.Equal(t, completion, at)
+	clock.Advance(time.Nanosecond)
+	_, ok = g.CompletedAt("key")
+	require.False(t, ok)
+}
@@ -0,0 +1,24 @@
+package inflight
+
+// defaultGroup is the group used by the package-level functions, such as
+// [DoString]. Its zero value is ready to use, so it needs no initialization.
+var defaultGroup Group[string, any]
+
+// DoString is like [Group.Do], called on a default Group[string, any] shared
+// by the whole program. It is meant for quick scripts: since the default
+// group is shared by all its users, they must pick keys which do not collide,
+// and type assert the returned values. Create a [Group] instead to get type
+// safety and isolation.
+//
+// DoString is safe for concurrent use by multiple goroutines.
+func DoString(key string, fn func() (any, error)) (any, bool, error) {
+	return defaultGroup.Do(key, fn)
+}
+
+// ForgetString is like [Group.Forget], called on the default group used by
+// [DoString].
+//
+// ForgetString is safe for concurrent use by multiple goroutines.
+func ForgetString(key string) {
+	defaultGroup.Forget(key)
+}
@@ -0,0 +1,54 @@
+package inflight
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoString(t *testing.T) {
+	var calls atomic.Int32
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			v, _, err := DoString("TestDoString", func() (any, error) {
+				calls.Add(1)
+				<-block
+				return 42, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, 42, v)
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestForgetString(t *testing.T) {
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		DoString("TestForgetString", func() (any, error) {
+			close(started)
+			<-block
+			return 1, nil
+		})
+	}()
+	<-started
+
+	ForgetString("TestForgetString")
+	v, shared, err := DoString("TestForgetString", func() (any, error) { return 2, nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, 2, v)
+	close(block)
+	<-done
+}
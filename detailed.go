@@ -0,0 +1,65 @@
+package inflight
+
+import (
+	"context"
+	"time"
+)
+
+// Info describes the role of a caller in a call, see [Group.DoDetailed].
+type Info struct {
+	// Shared reports whether the result was shared with other callers, like
+	// the bool returned by [Group.Do].
+	Shared bool
+	// Leader reports whether the caller started the call and executed its
+	// function. At most one caller of a call is its leader: with
+	// [WithBackgroundLeader], the function is executed in the background, and
+	// none of its callers is. A caller served by a cached result never is.
+	Leader bool
+	// Waiters is the number of callers which were waiting on the call when
+	// it completed, besides the one completing it. It is zero if the caller
+	// stopped waiting before the call completed.
+	Waiters int32
+	// Duration is the execution time of the function of the call, which is
+	// the same for all its callers. It is zero if the caller stopped waiting
+	// before the call completed.
+	Duration time.Duration
+	// LeaderChanges is the number of times a waiter was promoted to leader
+	// of the call, because the previous leader's context was done, see
+	// [Group.DoContext]. It is zero for a call executed once.
+	LeaderChanges int
+	// Deadline is the deadline the function of the call was executed with,
+	// the same for all its callers, or the zero time if it had none: the
+	// deadline of the context of its leader, or with [WithMergedContext] the
+	// latest deadline of its callers, bounded by [WithCallTimeout]. A cache
+	// layer can derive its own TTL from it.
+	Deadline time.Time
+}
+
+// DoDetailed is like [Group.Do], but returns an [Info] describing the role of
+// the caller in the call instead of a bool, to tell the leader apart from the
+// other callers for logging or fairness accounting.
+//
+// DoDetailed is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoDetailed(key K, fn func() (V, error)) (V, Info, error) {
+	return g.do(context.Background(), key, func(context.Context) (V, error) { return fn() }, callOptions{ctxless: true})
+}
+
+// DoDetailedContext is like [Group.DoContext], but returns an [Info]
+// describing the role of the caller in the call instead of a bool, to tell
+// calls executed once apart from calls whose leader changed.
+//
+// DoDetailedContext is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoDetailedContext(ctx context.Context, key K, fn func(context.Context) (V, error)) (V, Info, error) {
+	return g.do(ctx, key, fn, callOptions{})
+}
+
+// DoTimed is like [Group.Do], but also returns the execution time of the
+// function of the call, to measure the effective backend latency. Every
+// caller sharing the result gets the same duration, even the ones which
+// waited for less time, or got it from the cache.
+//
+// DoTimed is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoTimed(key K, fn func() (V, error)) (V, bool, time.Duration, error) {
+	value, info, err := g.DoDetailed(key, fn)
+	return value, info.Shared, info.Duration, err
+}
@@ -0,0 +1,175 @@
+package inflight
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoDetailed(t *testing.T) {
+	var g Group[string, string]
+
+	const n = 8
+	var leaders atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, info, err := g.DoDetailed("key", func() (string, error) {
+				time.Sleep(50 * time.Millisecond)
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "bar", v)
+			require.True(t, info.Shared)
+			require.Equal(t, int32(n-1), info.Waiters)
+			if info.Leader {
+				leaders.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), leaders.Load())
+
+	// A lone caller leads an unshared call.
+	_, info, err := g.DoDetailed("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, Info{Leader: true, Duration: info.Duration}, info)
+}
+
+func TestDoDetailedCached(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+
+	_, leader, err := g.DoDetailed("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, Info{Leader: true, Duration: leader.Duration}, leader)
+
+	// The duration of the execution is cached along with its result.
+	v, info, err := g.DoDetailed("key", func() (string, error) { panic("unreachable") })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+	require.Equal(t, Info{Shared: true, Duration: leader.Duration}, info)
+}
+
+func TestDoTimed(t *testing.T) {
+	var g Group[string, string]
+
+	const sleep = 50 * time.Millisecond
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			v, shared, d, err := g.DoTimed("key", func() (string, error) {
+				time.Sleep(sleep)
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, "bar", v)
+			require.GreaterOrEqual(t, d, sleep)
+		})
+	}
+	wg.Wait()
+}
+
+func TestDoDetailedContextLeaderChanges(t *testing.T) {
+	var g Group[string, string]
+
+	// A call executed once has no leader change.
+	_, info, err := g.DoDetailedContext(t.Context(), "key", func(context.Context) (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Zero(t, info.LeaderChanges)
+
+	leaderCtx, cancelLeader := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		_, _, err := g.DoDetailedContext(leaderCtx, "key", func(ctx context.Context) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Go(func() {
+			v, info, err := g.DoDetailedContext(t.Context(), "key", func(context.Context) (string, error) {
+				time.Sleep(10 * time.Millisecond)
+				return "promoted", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "promoted", v)
+			require.False(t, info.Leader)
+			require.Equal(t, 1, info.LeaderChanges)
+		})
+	}
+	time.Sleep(10 * time.Millisecond) // Ensure the waiters joined.
+
+	cancelLeader()
+	<-leaderDone
+	wg.Wait()
+}
+
+func TestDoDetailedContextDeadline(t *testing.T) {
+	now := time.Now()
+	deadlines := []time.Time{now.Add(time.Hour), now.Add(3 * time.Hour), now.Add(2 * time.Hour)}
+
+	// run calls the group once per deadline, and returns the Info of every
+	// caller along with the deadline of the leader.
+	run := func(t *testing.T, opts ...Option) (infos []Info, leader time.Time) {
+		g := New[string, string](append(opts, WithSynchronizer(NewSynchronizer(len(deadlines)-1)))...)
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, d := range deadlines {
+			wg.Go(func() {
+				ctx, cancel := context.WithDeadline(t.Context(), d)
+				defer cancel()
+				_, info, err := g.DoDetailedContext(ctx, "key", func(context.Context) (string, error) { return "bar", nil })
+				require.NoError(t, err)
+				mu.Lock()
+				defer mu.Unlock()
+				infos = append(infos, info)
+				if info.Leader {
+					leader = d
+				}
+			})
+		}
+		wg.Wait()
+		require.Len(t, infos, len(deadlines))
+		return infos, leader
+	}
+
+	t.Run("leader", func(t *testing.T) {
+		infos, leader := run(t)
+		for _, info := range infos {
+			require.Equal(t, leader, info.Deadline)
+		}
+	})
+
+	t.Run("merged", func(t *testing.T) {
+		infos, _ := run(t, WithMergedContext())
+		for _, info := range infos {
+			require.Equal(t, deadlines[1], info.Deadline)
+		}
+	})
+
+	t.Run("call_timeout", func(t *testing.T) {
+		infos, _ := run(t, WithMergedContext(), WithCallTimeout(time.Minute))
+		for _, info := range infos {
+			require.WithinDuration(t, time.Now().Add(time.Minute), info.Deadline, time.Second)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		var g Group[string, string]
+		_, info, err := g.DoDetailedContext(t.Context(), "key", func(context.Context) (string, error) { return "bar", nil })
+		require.NoError(t, err)
+		require.Zero(t, info.Deadline)
+	})
+}
@@ -0,0 +1,66 @@
+package inflight
+
+import "strconv"
+
+// EvictReason is the reason why a cached result was evicted from a [Group],
+// see [WithEvictionCallback].
+type EvictReason int
+
+const (
+	// Forgotten is the reason of the results removed by [Group.Forget],
+	// [Group.ForgetUnshared], [Group.Invalidate] and [Group.Reset].
+	Forgotten EvictReason = iota
+	// Expired is the reason of the results evicted once their TTL elapsed,
+	// either on access or by the janitor, see [WithJanitor].
+	Expired
+	// Replaced is the reason of the results replaced by a fresh one, see
+	// [Group.DoFresh].
+	Replaced
+	// Closed is the reason of the results removed by [Group.Close].
+	Closed
+	// Capacity is the reason of the least recently used results evicted
+	// beyond the capacity of the group, see [WithMaxEntries].
+	Capacity
+)
+
+// String returns the name of the reason.
+func (r EvictReason) String() string {
+	switch r {
+	case Forgotten:
+		return "Forgotten"
+	case Expired:
+		return "Expired"
+	case Replaced:
+		return "Replaced"
+	case Closed:
+		return "Closed"
+	case Capacity:
+		return "Capacity"
+	default:
+		return "EvictReason(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// WithEvictionCallback registers fn to be called once for every successful
+// cached result evicted from the group, with its key, its value and the
+// reason of the eviction. This lets callers keep external systems coherent
+// with the cache, for instance to release a reference held by the value.
+// Cached errors and calls evicted before completing are not reported.
+//
+// fn is called synchronously by the goroutine evicting the result, without
+// holding any internal lock.
+//
+// The type parameters of fn must match the ones of the group given the
+// option.
+func WithEvictionCallback[K comparable, V any](fn func(key K, value V, reason EvictReason)) Option {
+	return func(o *options) { o.evictionCallback = fn }
+}
+
+// onEvict calls the eviction callback if call has a successful cached
+// result, see [WithEvictionCallback]. It must be called once call was
+// removed from the store.
+func (h *hooks[K, V]) onEvict(key K, call *call[V], reason EvictReason) {
+	if h.evict != nil && call.cached() && call.err == nil {
+		h.evict(key, call.value, reason)
+	}
+}
@@ -0,0 +1,112 @@
+package inflight
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// eviction is an eviction reported to an eviction callback.
+type eviction struct {
+	key    string
+	value  string
+	reason EvictReason
+}
+
+// recordEvictions returns an option recording the evictions of a group.
+func recordEvictions() (Option, func() []eviction) {
+	var (
+		mu        sync.Mutex
+		evictions []eviction
+	)
+	opt := WithEvictionCallback(func(key string, value string, reason EvictReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		evictions = append(evictions, eviction{key, value, reason})
+	})
+	return opt, func() []eviction {
+		mu.Lock()
+		defer mu.Unlock()
+		return evictions
+	}
+}
+
+func mustDo(t *testing.T, g *Group[string, string], key, value string) {
+	t.Helper()
+	v, _, err := g.Do(key, func() (string, error) { return value, nil })
+	require.NoError(t, err)
+	require.Equal(t, value, v)
+}
+
+func TestWithEvictionCallbackForgotten(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := NewWithTTL[string, string](time.Minute, opt)
+
+	mustDo(t, g, "a", "1")
+	mustDo(t, g, "b", "2")
+	mustDo(t, g, "c", "3")
+	mustDo(t, g, "d", "4")
+	g.Forget("a")
+	g.Forget("a")
+	require.True(t, g.ForgetUnshared("b"))
+	g.Invalidate("c")
+	g.Reset()
+	require.Equal(t, []eviction{
+		{"a", "1", Forgotten},
+		{"b", "2", Forgotten},
+		{"c", "3", Forgotten},
+		{"d", "4", Forgotten},
+	}, evictions())
+}
+
+func TestWithEvictionCallbackExpired(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := NewWithTTL[string, string](10*time.Millisecond, opt, WithJanitor(5*time.Millisecond))
+	defer g.Close()
+
+	mustDo(t, g, "key", "1")
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, []eviction{{"key", "1", Expired}}, evictions())
+
+	// Expired on access.
+	g = NewWithTTL[string, string](10*time.Millisecond, opt)
+	mustDo(t, g, "other", "2")
+	time.Sleep(20 * time.Millisecond)
+	mustDo(t, g, "other", "3")
+	require.Equal(t, []eviction{{"key", "1", Expired}, {"other", "2", Expired}}, evictions())
+}
+
+func TestWithEvictionCallbackReplaced(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := NewWithTTL[string, string](time.Minute, opt)
+
+	mustDo(t, g, "key", "1")
+	v, _, err := g.DoFresh("key", func() (string, error) { return "2", nil })
+	require.NoError(t, err)
+	require.Equal(t, "2", v)
+	require.Equal(t, []eviction{{"key", "1", Replaced}}, evictions())
+}
+
+func TestWithEvictionCallbackClosed(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := NewWithTTL[string, string](time.Minute, opt, WithErrorTTL(time.Minute))
+
+	mustDo(t, g, "key", "1")
+	// Cached errors are not reported.
+	_, _, err := g.Do("err", func() (string, error) { return "", errors.New("some error") })
+	require.Error(t, err)
+
+	require.NoError(t, g.Close())
+	require.NoError(t, g.Close())
+	require.Equal(t, []eviction{{"key", "1", Closed}}, evictions())
+	require.Zero(t, g.Len())
+}
+
+func TestEvictReasonString(t *testing.T) {
+	require.Equal(t, "Forgotten", Forgotten.String())
+	require.Equal(t, "Closed", Closed.String())
+	require.Equal(t, "EvictReason(42)", EvictReason(42).String())
+}
@@ -0,0 +1,98 @@
+package inflight
+
+import (
+	"context"
+	"sync"
+)
+
+// WithExecutionKey serializes the executions of the functions of the group
+// whose keys are mapped to the same execution key by executionKey: a leader
+// waits for the function of any other call sharing its execution key to
+// return before executing its own, even if their keys differ. This protects a
+// shared resource which is not safe for concurrent use, and keyed differently
+// than the calls, such as a connection per shard. Keys mapped to distinct
+// execution keys still execute in parallel.
+//
+// executionKey must return a comparable value, and may return nil for the
+// keys whose executions need not be serialized. A leader waiting for its turn
+// gives up once the context given to [Group.DoContext] is done, as if its
+// function returned the context error. The executions of [Group.DoMulti] are
+// not serialized.
+//
+// The type parameter of executionKey must match the key type of the group
+// given the option.
+func WithExecutionKey[K comparable](executionKey func(K) any) Option {
+	return func(o *options) { o.executionKey = executionKey }
+}
+
+// keyLocks is a set of locks, one per execution key, see [WithExecutionKey].
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[any]*keyLock
+}
+
+// keyLock is the lock of an execution key, held by the function executing.
+type keyLock struct {
+	ch   chan struct{} // holds a token while the lock is held.
+	refs int           // number of leaders holding or waiting for the lock.
+}
+
+// acquire locks the execution key k, unless ctx is done first.
+func (l *keyLocks) acquire(ctx context.Context, k any) error {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[any]*keyLock)
+	}
+	lock := l.locks[k]
+	if lock == nil {
+		lock = &keyLock{ch: make(chan struct{}, 1)}
+		l.locks[k] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+	select {
+	case lock.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		l.unref(k, lock)
+		return ctx.Err()
+	}
+}
+
+// release unlocks the execution key k, which must be locked.
+func (l *keyLocks) release(k any) {
+	l.mu.Lock()
+	lock := l.locks[k]
+	l.mu.Unlock()
+	<-lock.ch
+	l.unref(k, lock)
+}
+
+// unref drops a reference to lock, the lock of k, deleting it once unused.
+func (l *keyLocks) unref(k any, lock *keyLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lock.refs--; lock.refs == 0 {
+		delete(l.locks, k)
+	}
+}
+
+// serialized wraps fn, executed for key, so that it holds the lock of the
+// execution key of key while executing, see [WithExecutionKey].
+func (h *hooks[K, V]) serialized(key K, fn func(context.Context) (V, error)) func(context.Context) (V, error) {
+	if h.executionKey == nil {
+		return fn
+	}
+	k := h.executionKey(key)
+	if k == nil {
+		return fn
+	}
+	return func(ctx context.Context) (V, error) {
+		if err := h.locks.acquire(ctx, k); err != nil {
+			var zero V
+			return zero, err
+		}
+		defer h.locks.release(k)
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,62 @@
+package inflight
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithExecutionKey(t *testing.T) {
+	// Keys are "shard/id", the executions of a shard are serialized.
+	g := New[string, string](WithExecutionKey(func(key string) any {
+		shard, _, _ := strings.Cut(key, "/")
+		return shard
+	}))
+
+	var running, maxRunning atomic.Int32
+	fn := func() (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		return "", nil
+	}
+	var wg sync.WaitGroup
+	for _, key := range []string{"a/1", "a/2", "a/3", "a/4"} {
+		wg.Go(func() { g.Do(key, fn) })
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), maxRunning.Load())
+	require.Empty(t, g.hooks.locks.locks)
+
+	// Distinct execution keys execute in parallel.
+	block := make(chan struct{})
+	started := make(chan struct{})
+	wg.Go(func() {
+		g.Do("a/1", func() (string, error) {
+			close(started)
+			<-block
+			return "", nil
+		})
+	})
+	<-started
+	v, _, err := g.Do("b/1", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+
+	// A leader waiting for its turn gives up once its context is done.
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = g.DoContext(ctx, "a/2", func(context.Context) (string, error) { panic("unreachable") })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(block)
+	wg.Wait()
+	require.Empty(t, g.hooks.locks.locks)
+}
@@ -0,0 +1,63 @@
+package inflight
+
+import "runtime"
+
+// Fetcher is a read-through cache which always fetches the value of a key
+// with the same function, deduplicating concurrent fetches of a key with a
+// [Group].
+//
+// Fetcher is safe for concurrent use by multiple goroutines.
+type Fetcher[K comparable, V any] struct {
+	g     *Group[K, V]
+	fetch func(K) (V, error)
+}
+
+// NewFetcher creates a new [Fetcher] which fetches the value of a key with
+// fetch. The options configure the underlying [Group], for instance
+// [WithTTL] caches the fetched values.
+func NewFetcher[K comparable, V any](fetch func(K) (V, error), opts ...Option) *Fetcher[K, V] {
+	return &Fetcher[K, V]{g: New[K, V](opts...), fetch: fetch}
+}
+
+// Get is like [Group.Do], with a function calling fetch(key).
+func (f *Fetcher[K, V]) Get(key K) (V, bool, error) {
+	return f.g.Do(key, func() (V, error) { return f.fetch(key) })
+}
+
+// Forget is like [Group.Forget].
+func (f *Fetcher[K, V]) Forget(key K) {
+	f.g.Forget(key)
+}
+
+// Stats is like [Group.Stats].
+func (f *Fetcher[K, V]) Stats() Stats {
+	return f.g.Stats()
+}
+
+// Close is like [Group.Close].
+func (f *Fetcher[K, V]) Close() error {
+	return f.g.Close()
+}
+
+// Memoize returns a function deduplicating the concurrent calls to fn with
+// the same key, and caching their results according to opts, for instance
+// with [WithTTL]. It is the simplest way to use the package, when the group
+// itself does not need to be accessed.
+//
+// Since the underlying group can't be closed by the caller, it is closed once
+// the returned function is garbage collected, which stops its janitor, see
+// [WithJanitor]. Use a [Fetcher] to close it deterministically instead.
+//
+// The returned function is safe for concurrent use by multiple goroutines.
+func Memoize[K comparable, V any](fn func(K) (V, error), opts ...Option) func(K) (V, error) {
+	f := NewFetcher(fn, opts...)
+	if f.g.opts.janitor > 0 {
+		// The janitor references the group, but not the fetcher, which is
+		// only referenced by the returned function.
+		runtime.AddCleanup(f, func(g *Group[K, V]) { g.Close() }, f.g)
+	}
+	return func(key K) (V, error) {
+		value, _, err := f.Get(key)
+		return value, err
+	}
+}
@@ -0,0 +1,134 @@
+package inflight
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetcher(t *testing.T) {
+	var calls atomic.Int32
+	f := NewFetcher(func(key int) (string, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return strconv.Itoa(key), nil
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			v, _, err := f.Get(42)
+			require.NoError(t, err)
+			require.Equal(t, "42", v)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+	require.Equal(t, Stats{Calls: 8, Executions: 1, Shared: 8}, f.Stats())
+}
+
+func TestFetcherTTL(t *testing.T) {
+	var calls atomic.Int32
+	f := NewFetcher(func(key int) (string, error) {
+		calls.Add(1)
+		return strconv.Itoa(key), nil
+	}, WithTTL(time.Minute))
+
+	for range 3 {
+		v, _, err := f.Get(42)
+		require.NoError(t, err)
+		require.Equal(t, "42", v)
+	}
+	require.Equal(t, int32(1), calls.Load())
+
+	f.Forget(42)
+	_, _, err := f.Get(42)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestMemoize(t *testing.T) {
+	var calls [2]atomic.Int32
+	block := make(chan struct{})
+	get := Memoize(func(key int) (string, error) {
+		calls[key].Add(1)
+		<-block
+		return strconv.Itoa(key), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			key := i % 2
+			v, err := get(key)
+			require.NoError(t, err)
+			require.Equal(t, strconv.Itoa(key), v)
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls[0].Load())
+	require.Equal(t, int32(1), calls[1].Load())
+
+	// Without a TTL, the next batch executes again.
+	_, err := get(0)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), calls[0].Load())
+}
+
+func TestMemoizeTTL(t *testing.T) {
+	var calls atomic.Int32
+	get := Memoize(func(key int) (string, error) {
+		calls.Add(1)
+		return strconv.Itoa(key), nil
+	}, WithTTL(time.Minute))
+
+	for range 3 {
+		v, err := get(42)
+		require.NoError(t, err)
+		require.Equal(t, "42", v)
+	}
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestFetcherClose(t *testing.T) {
+	f := NewFetcher(func(key string) (string, error) { return key, nil }, WithTTL(time.Minute), WithJanitor(time.Millisecond))
+	require.NoError(t, f.Close())
+	_, _, err := f.Get("key")
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestMemoizeJanitor(t *testing.T) {
+	closed := make(chan struct{})
+	func() {
+		get := Memoize(func(key string) (string, error) { return key, nil },
+			WithTTL(time.Minute),
+			WithJanitor(time.Millisecond),
+			WithEvictionCallback(func(key, value string, reason EvictReason) {
+				if reason == Closed {
+					close(closed)
+				}
+			}),
+		)
+		v, err := get("key")
+		require.NoError(t, err)
+		require.Equal(t, "key", v)
+	}()
+
+	// The group is closed once the function is garbage collected.
+	require.Eventually(t, func() bool {
+		runtime.GC()
+		select {
+		case <-closed:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,78 @@
+package inflight
+
+import (
+	"context"
+	"time"
+)
+
+// DoFresh always executes fn and returns its result, even if a call for key
+// is already in flight: it does not join it. This is useful for cache-busting
+// refreshes, where a caller must get a fresh result.
+//
+// Once fn completes, its result replaces the entry of the key, so that
+// subsequent callers pick up the fresh value if it is cached (see
+// [NewWithTTL]) instead of a stale one, and do not join a stale in-flight
+// call otherwise. A call already in flight keeps executing and serving its
+// existing waiters. A failing fn, whose error is not cached, leaves the entry
+// of the key untouched: its cached result or call in flight is kept.
+//
+// Concurrent DoFresh calls for the same key each execute their function, the
+// last one to complete wins the entry of the key.
+//
+// The returned bool is always false, since the result is never shared with
+// callers in flight.
+//
+// DoFresh is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoFresh(key K, fn func() (V, error)) (V, bool, error) {
+	g.stats.calls.Add(1)
+	if g.closed.Load() {
+		var zero V
+		return zero, false, ErrClosed
+	}
+	if observe := g.opts.waitHistogram; observe != nil {
+		start := time.Now()
+		defer func() { observe(time.Since(start)) }()
+	}
+	g.stats.executions.Add(1)
+	g.running.start()
+	defer g.running.stop()
+	done := g.hooks.onStart(context.Background(), key)
+	start := time.Now()
+	call := newCall[V](&g.opts)
+	defer call.leave()
+	var (
+		value V
+		err   error
+	)
+	execute(context.Background(), &g.opts, g.wrap(key, func(context.Context) (V, error) { return fn() }, false), func(v V, e error, p any) {
+		call.elapsed = time.Since(start)
+		g.stats.failed(e, p)
+		failure := e
+		if p == nil {
+			v, e = g.hooks.rescue(key, call, v, e)
+			g.hooks.admission(key, call, v, e)
+		}
+		cached, _ := call.complete(v, e, p)
+		if !g.opts.disabled && (cached || call.kept || e == nil && p == nil) {
+			if previous, ok := g.store().Swap(key, call); ok {
+				g.evicted(key, previous, Replaced)
+			}
+			switch {
+			case cached:
+				g.cache(key, call)
+			case call.kept:
+				g.forgetAfter(key, call)
+			default:
+				g.store().CompareAndDelete(key, call)
+			}
+		}
+		call.publish()
+		if p != nil {
+			g.hooks.onPanicked(done, p, call.elapsed)
+			return
+		}
+		value, err = v, e
+		g.hooks.onFinish(done, key, false, failure, call.elapsed)
+	})
+	return value, false, err
+}
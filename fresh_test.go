@@ -0,0 +1,101 @@
+package inflight
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoFresh(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, _, err := g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "stale", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "stale", v)
+	}()
+	<-started
+
+	var calls atomic.Int32
+	v, shared, err := g.DoFresh("key", func() (string, error) {
+		calls.Add(1)
+		return "fresh", nil
+	})
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "fresh", v)
+	require.Equal(t, int32(1), calls.Load())
+
+	// The in-flight call completes, but the fresh result stays cached.
+	close(block)
+	<-done
+	v, shared, err = g.Do("key", func() (string, error) {
+		t.Error("the fresh result must be cached")
+		return "", nil
+	})
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, "fresh", v)
+}
+
+func TestDoFreshNotCached(t *testing.T) {
+	var g Group[string, string]
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	go g.Do("key", func() (string, error) {
+		close(started)
+		<-block
+		return "stale", nil
+	})
+	<-started
+	defer close(block)
+
+	g.DoFresh("key", func() (string, error) { return "fresh", nil })
+
+	// The stale in-flight call is not joinable anymore.
+	v, shared, err := g.Do("key", func() (string, error) { return "new", nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "new", v)
+}
+
+func TestDoFreshError(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := NewWithTTL[string, string](time.Minute, opt)
+	mustDo(t, g, "key", "bar")
+
+	// A failing refresh keeps the cached result.
+	someErr := errors.New("some error")
+	_, _, err := g.DoFresh("key", func() (string, error) { return "", someErr })
+	require.ErrorIs(t, err, someErr)
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+	require.Empty(t, evictions())
+
+	// And the call in flight.
+	started := make(chan struct{})
+	block := make(chan struct{})
+	go g.Do("other", func() (string, error) {
+		close(started)
+		<-block
+		return "baz", nil
+	})
+	<-started
+	_, _, err = g.DoFresh("other", func() (string, error) { return "", someErr })
+	require.ErrorIs(t, err, someErr)
+	require.True(t, g.InFlight("other"))
+	close(block)
+}
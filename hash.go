@@ -0,0 +1,166 @@
+package inflight
+
+import "github.com/go4org/hashtriemap"
+
+// WithKeyHasher makes the group look up its calls by hash(key) instead of
+// hashing the keys with the runtime hasher. This is meant for large keys
+// which are expensive to hash, such as big structs, for which a cheaper hash
+// can be derived, for instance from an identifier field.
+//
+// Keys are still compared with == within a hash: keys colliding on the same
+// hash are stored side by side and never share a call, collisions only cost
+// a few extra comparisons. A hash function returning evenly distributed
+// values keeps them rare.
+//
+// The type parameter of hash must match the key type of the group given the
+// option. The option is ignored by [NewWithStore].
+func WithKeyHasher[K comparable](hash func(K) uint64) Option {
+	return func(o *options) { o.keyHasher = hash }
+}
+
+// hashedStore is a [store] which looks up its entries by a hash of their key,
+// see [WithKeyHasher]. Its buckets are immutable, they are replaced as a
+// whole on every update.
+type hashedStore[K, V comparable] struct {
+	hash func(K) uint64
+	m    hashtriemap.HashTrieMap[uint64, *bucket[K, V]]
+}
+
+// bucket holds the entries of the keys sharing a hash.
+type bucket[K, V comparable] struct {
+	keys   []K
+	values []V
+}
+
+// get returns the value of key in b, which may be nil.
+func (b *bucket[K, V]) get(key K) (V, bool) {
+	if b != nil {
+		for i, k := range b.keys {
+			if k == key {
+				return b.values[i], true
+			}
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// with returns a copy of b, which may be nil, where key is set to value.
+func (b *bucket[K, V]) with(key K, value V) *bucket[K, V] {
+	nb := &bucket[K, V]{}
+	if b != nil {
+		for i, k := range b.keys {
+			if k != key {
+				nb.keys = append(nb.keys, k)
+				nb.values = append(nb.values, b.values[i])
+			}
+		}
+	}
+	nb.keys = append(nb.keys, key)
+	nb.values = append(nb.values, value)
+	return nb
+}
+
+// without returns a copy of b without key, or nil if it has no other key.
+func (b *bucket[K, V]) without(key K) *bucket[K, V] {
+	var nb *bucket[K, V]
+	for i, k := range b.keys {
+		if k != key {
+			if nb == nil {
+				nb = &bucket[K, V]{}
+			}
+			nb.keys = append(nb.keys, k)
+			nb.values = append(nb.values, b.values[i])
+		}
+	}
+	return nb
+}
+
+// update atomically updates the entry of key: f is called with its current
+// value, and returns its new value, whether it must be deleted instead, and
+// whether the entry must be changed at all. f may be called several times
+// under contention. update returns the value of key before the change.
+func (s *hashedStore[K, V]) update(key K, f func(old V, loaded bool) (new V, del, change bool)) (V, bool) {
+	h := s.hash(key)
+	for {
+		b, exists := s.m.Load(h)
+		old, loaded := b.get(key)
+		value, del, change := f(old, loaded)
+		if !change {
+			return old, loaded
+		}
+		var updated bool
+		switch {
+		case del:
+			// A deleted key was loaded, so its bucket exists.
+			if nb := b.without(key); nb == nil {
+				updated = s.m.CompareAndDelete(h, b)
+			} else {
+				updated = s.m.CompareAndSwap(h, b, nb)
+			}
+		case !exists:
+			_, taken := s.m.LoadOrStore(h, b.with(key, value))
+			updated = !taken
+		default:
+			updated = s.m.CompareAndSwap(h, b, b.with(key, value))
+		}
+		if updated {
+			return old, loaded
+		}
+	}
+}
+
+func (s *hashedStore[K, V]) Load(key K) (V, bool) {
+	b, _ := s.m.Load(s.hash(key))
+	return b.get(key)
+}
+
+func (s *hashedStore[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	actual, loaded := s.update(key, func(_ V, loaded bool) (V, bool, bool) {
+		return value, false, !loaded
+	})
+	if !loaded {
+		actual = value
+	}
+	return actual, loaded
+}
+
+func (s *hashedStore[K, V]) LoadAndDelete(key K) (V, bool) {
+	return s.update(key, func(old V, loaded bool) (V, bool, bool) {
+		return old, true, loaded
+	})
+}
+
+func (s *hashedStore[K, V]) Swap(key K, value V) (V, bool) {
+	return s.update(key, func(V, bool) (V, bool, bool) {
+		return value, false, true
+	})
+}
+
+func (s *hashedStore[K, V]) CompareAndSwap(key K, old, new V) bool {
+	swapped := false
+	s.update(key, func(value V, loaded bool) (V, bool, bool) {
+		swapped = loaded && value == old
+		return new, false, swapped
+	})
+	return swapped
+}
+
+func (s *hashedStore[K, V]) CompareAndDelete(key K, old V) bool {
+	deleted := false
+	s.update(key, func(value V, loaded bool) (V, bool, bool) {
+		deleted = loaded && value == old
+		return value, true, deleted
+	})
+	return deleted
+}
+
+func (s *hashedStore[K, V]) Range(yield func(K, V) bool) {
+	for _, b := range s.m.Range {
+		for i, key := range b.keys {
+			if !yield(key, b.values[i]) {
+				return
+			}
+		}
+	}
+}
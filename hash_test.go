@@ -0,0 +1,91 @@
+package inflight
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithKeyHasherCollisions(t *testing.T) {
+	// Every key collides on the same hash.
+	g := New[string, string](WithKeyHasher(func(string) uint64 { return 0 }), WithTTL(time.Minute))
+
+	const n = 8
+	var calls [n]atomic.Int32
+	var wg sync.WaitGroup
+	for i := range n {
+		for range 4 {
+			wg.Go(func() {
+				key := strconv.Itoa(i)
+				v, _, err := g.Do(key, func() (string, error) {
+					calls[i].Add(1)
+					time.Sleep(20 * time.Millisecond)
+					return key, nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, key, v)
+			})
+		}
+	}
+	wg.Wait()
+
+	for i := range n {
+		require.Equal(t, int32(1), calls[i].Load())
+	}
+	require.Equal(t, n, g.Len())
+	require.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6", "7"}, slices.Sorted(g.Keys()))
+
+	g.Forget("3")
+	_, ok := g.Peek("3")
+	require.False(t, ok)
+	v, ok := g.Peek("4")
+	require.True(t, ok)
+	require.Equal(t, "4", v)
+
+	g.Reset()
+	require.Zero(t, g.Len())
+}
+
+func TestWithKeyHasherTypeMismatch(t *testing.T) {
+	require.Panics(t, func() {
+		New[string, string](WithKeyHasher(func(int) uint64 { return 0 }))
+	})
+}
+
+// bigKey is an expensive key to hash with the runtime hasher.
+type bigKey struct {
+	id      uint64
+	payload [512]byte
+}
+
+func BenchmarkKeyHasher(b *testing.B) {
+	keys := make([]bigKey, 1024)
+	for i := range keys {
+		keys[i].id = uint64(i)
+		keys[i].payload[0] = byte(i)
+	}
+	fn := func() (int, error) { return 0, nil }
+
+	for _, bc := range []struct {
+		name string
+		g    *Group[bigKey, int]
+	}{
+		{"Default", New[bigKey, int](WithTTL(time.Hour))},
+		{"Custom", New[bigKey, int](WithTTL(time.Hour), WithKeyHasher(func(k bigKey) uint64 { return k.id * 0x9e3779b97f4a7c15 }))},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					bc.g.Do(keys[i%len(keys)], fn)
+					i++
+				}
+			})
+		})
+	}
+}
@@ -7,36 +7,77 @@
 package inflight
 
 import (
-	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
 	"sync/atomic"
+	"time"
 
 	"github.com/go4org/hashtriemap"
 )
 
-// call represents a single in-flight function execution.
-// It tracks the number of concurrent callers and ensures the function
-// is executed exactly once using [sync.OnceValues].
-type call[T any] struct {
-	callers  atomic.Int32      // number of callers currently executing [call.do].
-	onceFunc func() (T, error) // function wrapped with [sync.OnceValues].
+// errGoexit is returned to the waiters of a call whose function called
+// [runtime.Goexit] instead of returning.
+var errGoexit = errors.New("inflight: runtime.Goexit was called")
+
+// errWouldBlock is returned internally to a caller of [Group.TryDo] finding
+// a call in flight.
+var errWouldBlock = errors.New("inflight: call in flight")
+
+// ErrTooManyWaiters is returned to a caller which can't join an in-flight
+// call because it already has the maximum number of waiters, see
+// [WithMaxWaiters].
+var ErrTooManyWaiters = errors.New("inflight: too many waiters")
+
+// ErrFlushed is returned to the callers of the calls in flight when
+// [Group.Flush] is called.
+var ErrFlushed = errors.New("inflight: call flushed")
+
+// ErrClosed is returned by the calls made to a [Group] after it was closed,
+// see [Group.Close].
+var ErrClosed = errors.New("inflight: group closed")
+
+// ErrReentrant is returned to a caller of [Group.DoContext] joining the call
+// it is executing the function of, which would otherwise wait on itself
+// forever. This is detected through the context passed to the function, see
+// [Group.DoContext].
+var ErrReentrant = errors.New("inflight: re-entrant call")
+
+// PanicError is the error returned to the callers of a call whose function
+// panicked, when the group is configured with [WithPanicToError].
+type PanicError struct {
+	Value any    // value passed to panic.
+	Stack []byte // stack trace of the panicking goroutine.
 }
 
-// newCall creates a new [call] instance that wraps fn with [sync.OnceValues]
-// to ensure it is executed exactly once.
-func newCall[T any](fn func() (T, error)) *call[T] {
-	return &call[T]{onceFunc: sync.OnceValues(fn)}
+// Error implements the error interface.
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("inflight: function panicked: %v\n\n%s", p.Value, p.Stack)
 }
 
-// do executes the [call.onceFunc] and returns the result along with the number
-// of concurrent callers at the time of completion.
-// The callers count helps determine if the result is being shared.
-func (c *call[T]) do() (T, int32, error) {
-	c.callers.Add(1)
-	defer c.callers.Add(-1)
-	value, err := c.onceFunc()
-	return value, c.callers.Load(), err
+// Unwrap returns the value passed to panic if it is an error, nil otherwise.
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
 }
 
+// SharedError is the error returned to the callers which did not execute the
+// function of a call but got its error, when the group is configured with
+// [WithErrorWrap]. The leader of the call gets Err unwrapped.
+type SharedError struct {
+	Err     error // error returned by the function.
+	Waiters int32 // number of callers which were waiting on the call, see [Info].
+}
+
+// Error implements the error interface.
+func (s *SharedError) Error() string {
+	return fmt.Sprintf("inflight: shared error (%d waiters): %v", s.Waiters, s.Err)
+}
+
+// Unwrap returns the error returned by the function.
+func (s *SharedError) Unwrap() error { return s.Err }
+
 // Group represents a collection of in-flight function calls, keyed by K.
 // It deduplicates concurrent calls with the same key, ensuring the function
 // is executed only once while sharing the result with all waiters.
@@ -44,7 +85,83 @@ func (c *call[T]) do() (T, int32, error) {
 // Group is safe for concurrent use by multiple goroutines.
 // The zero value of Group is ready to use.
 type Group[K comparable, V any] struct {
-	m hashtriemap.HashTrieMap[K, *call[V]]
+	m       hashtriemap.HashTrieMap[K, *call[V]] // default store.
+	custom  store[K, *call[V]]                   // see [NewWithStore].
+	opts    options
+	hooks   hooks[K, V]
+	stats   stats
+	running running       // see [Group.Wait].
+	lru     *lru[K, V]    // see [WithMaxEntries].
+	closed  atomic.Bool   // see [Group.Close].
+	stop    chan struct{} // closed by [Group.Close] to stop the janitor, see [WithJanitor].
+}
+
+// New creates a new [Group] configured with the given options.
+//
+// New panics if a generic option, such as [WithObserver], was given for
+// other type parameters than K and V.
+func New[K comparable, V any](opts ...Option) *Group[K, V] {
+	return newGroup[K, V](nil, opts)
+}
+
+// newGroup creates a new [Group] backed by s, or by the default store if s is
+// nil, configured with opts.
+func newGroup[K comparable, V any](s store[K, *call[V]], opts []Option) *Group[K, V] {
+	g := &Group[K, V]{custom: s}
+	for _, opt := range opts {
+		opt(&g.opts)
+	}
+	g.init()
+	return g
+}
+
+// init sets up the state of a group derived from its options.
+func (g *Group[K, V]) init() {
+	g.hooks = newHooks[K, V](&g.opts)
+	g.lru = newLRU[K, V](g.opts.maxEntries, typedOption[func(V) int]("WithSizer", g.opts.sizer))
+	if hash := typedOption[func(K) uint64]("WithKeyHasher", g.opts.keyHasher); hash != nil && g.custom == nil {
+		g.custom = &hashedStore[K, *call[V]]{hash: hash}
+	}
+	if normalize := typedOption[func(K) K]("WithKeyNormalizer", g.opts.keyNormalizer); normalize != nil {
+		g.custom = normalizedStore[K, *call[V]]{s: g.store(), normalize: normalize}
+	}
+	if d := g.opts.janitor; d > 0 {
+		g.stop = make(chan struct{})
+		go g.sweep(d)
+	}
+}
+
+// Clone creates a new empty [Group] configured with the same options as g,
+// such as its TTL, limits and observer. This is handy to build several groups
+// sharing the same policy.
+//
+// The clone shares no state with g: neither its in-flight calls, its cached
+// results nor its statistics are copied, and its concurrency limit (see
+// [WithMaxConcurrency]) is independent. A clone of a group created by
+// [NewWithStore] uses the default store.
+//
+// Clone is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Clone() *Group[K, V] {
+	c := &Group[K, V]{opts: g.opts}
+	if c.opts.sem != nil {
+		c.opts.sem = make(chan struct{}, cap(c.opts.sem))
+	}
+	c.init()
+	return c
+}
+
+// NewWithTTL creates a new [Group] which caches successful results for d once
+// their call completes.
+//
+// Calls to [Group.Do] arriving within d of the completion of a call reuse its
+// value with shared=true, without executing their function. Expired results
+// are lazily evicted on the next access to their key. Errors are not cached
+// unless [WithErrorTTL] is given, the next call for a key whose function
+// failed executes again.
+//
+// [Group.Forget] also removes cached results.
+func NewWithTTL[K comparable, V any](d time.Duration, opts ...Option) *Group[K, V] {
+	return New[K, V](append([]Option{WithTTL(d)}, opts...)...)
 }
 
 // Do executes and returns the result of the given function for the specified key,
@@ -60,16 +177,476 @@ type Group[K comparable, V any] struct {
 //
 // Do is safe for concurrent use by multiple goroutines.
 func (g *Group[K, V]) Do(key K, fn func() (V, error)) (V, bool, error) {
-	call, loaded := g.m.LoadOrStore(key, newCall(fn))
-	if !loaded { // This goroutine stored the [call], it owns the deletion as well.
-		defer g.m.CompareAndDelete(key, call)
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) { return fn() }, callOptions{ctxless: true})
+	return value, info.Shared, err
+}
+
+// DoKey is like [Group.Do], but passes key to fn, which can then be a plain
+// function or a method value shared by all the calls, for readability. The
+// group still wraps fn in a closure for every call.
+//
+// DoKey is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoKey(key K, fn func(K) (V, error)) (V, bool, error) {
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) { return fn(key) }, callOptions{ctxless: true})
+	return value, info.Shared, err
+}
+
+// DoLocked is like [Group.Do], but also calls after with the value returned by
+// fn, once per execution of fn which succeeds, before the result is
+// published to the waiters of the call. after thus runs exactly once per
+// deduplicated call, while the leader still owns the key, which lets it
+// update external indexes consistently: the waiters only return once after
+// returned.
+//
+// after runs on the goroutine of the leader, and is not called if fn returns
+// an error, or a value rejected by the validator of the group (see
+// [WithValidator]). A panic of after is handled like a panic of fn.
+//
+// DoLocked is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoLocked(key K, fn func() (V, error), after func(V)) (V, bool, error) {
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) {
+		v, err := g.hooks.validation(fn())
+		if err == nil {
+			after(v)
+		}
+		return v, err
+	}, callOptions{ctxless: true, validated: true})
+	return value, info.Shared, err
+}
+
+// DoCount is like [Group.Do], but fn receives the number of callers waiting
+// on the call besides its leader, sampled right before fn executes. This lets
+// fetchers adapt to the popularity of a key, for instance by fetching more
+// aggressively for keys many callers wait on.
+//
+// Since callers can still join the call once the count is sampled, it is a
+// lower bound of the number of callers which get the result.
+//
+// DoCount is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoCount(key K, fn func(waiters int32) (V, error)) (V, bool, error) {
+	var waiters int32
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) { return fn(waiters) }, callOptions{waiters: &waiters, ctxless: true})
+	return value, info.Shared, err
+}
+
+// DoContext is like [Group.Do], but the function receives a context and the
+// caller stops waiting once ctx is done.
+//
+// The leader (the caller that actually executes fn) passes its own ctx to fn.
+// If fn returns an error once the leader's ctx is done while other callers
+// are still waiting, the leader steps down instead of propagating its
+// cancellation: it returns the zero value of V, shared=true and ctx.Err(),
+// and one of the remaining callers is promoted to leader and executes its
+// own fn with its own context. This guarantees that a function keeps
+// running as long as at least one caller has a context which is not done,
+// provided fn honors its context. The leader always returns once fn returns.
+// A leader whose ctx is already done does not execute fn at all: it steps
+// down or returns ctx.Err() right away, so that a caller with a healthy
+// context can lead the call instead.
+// See [WithMergedContext] to run fn with a context shared by all the callers
+// instead.
+//
+// Whatever the configuration, fn only sees the values of the context of the
+// caller leading the call: the values of the other callers' contexts, such as
+// distinct trace IDs, are ignored. An [Observer] implementing [StartObserver]
+// gets the context passed to fn, to tell which caller led the call.
+//
+// A non-leader caller whose ctx is done before the call completes returns
+// immediately with the zero value of V, shared=true and ctx.Err(). The
+// in-flight call keeps running for the remaining callers. Such a caller is no
+// longer counted as a caller of the call: the shared bool returned to the
+// other callers only reflects the callers which actually received the result.
+// A result already available when the caller notices its ctx is done takes
+// precedence over the cancellation: the caller gets the result instead.
+//
+// If the group is configured with [WithCallTimeout], the context passed to fn
+// also expires once the timeout elapses.
+//
+// fn must not call the group for key, directly or indirectly, with any other
+// context than the one it receives or a context derived from it: such a
+// re-entrant call would wait on itself forever. With the context received by
+// fn, it returns [ErrReentrant] instead. The functions given to [Group.Do]
+// and its other context-free variants have no such context: their re-entrant
+// calls are not detected, and wait on themselves forever.
+//
+// DoContext is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoContext(ctx context.Context, key K, fn func(context.Context) (V, error)) (V, bool, error) {
+	value, info, err := g.do(ctx, key, fn, callOptions{})
+	return value, info.Shared, err
+}
+
+// DoPriority is like [Group.DoContext], but the caller waits for the call in
+// flight with the given priority: once its leader steps down, the waiter with
+// the highest priority is promoted to leader and executes its function,
+// instead of an arbitrary one. This lets latency-sensitive tiers take over
+// the calls first. Waiters with the same priority are promoted in no
+// particular order, and the callers of [Group.DoContext] have priority 0.
+//
+// The priority does not affect which caller leads a call in the first place:
+// the first caller does, whatever its priority.
+//
+// DoPriority is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoPriority(ctx context.Context, key K, priority int, fn func(context.Context) (V, error)) (V, bool, error) {
+	value, info, err := g.do(ctx, key, fn, callOptions{priority: priority})
+	return value, info.Shared, err
+}
+
+// do implements [Group.Do] and its variants, configured with co.
+func (g *Group[K, V]) do(ctx context.Context, key K, fn func(context.Context) (V, error), co callOptions) (V, Info, error) {
+	g.stats.calls.Add(1)
+	if g.closed.Load() {
+		var zero V
+		return zero, Info{}, ErrClosed
+	}
+	if observe := g.opts.waitHistogram; observe != nil {
+		start := time.Now()
+		defer func() { observe(time.Since(start)) }()
+	}
+	return g.join(ctx, key, fn, co)
+}
+
+// join joins the call for key, or stores and leads a new one.
+func (g *Group[K, V]) join(ctx context.Context, key K, fn func(context.Context) (V, error), co callOptions) (V, Info, error) {
+	call, loaded := g.load(key)
+	if loaded && call.completed() && !co.skipCache {
+		g.lru.touch(call)
+		g.stats.shared.Add(1)
+		g.hooks.onJoin(ctx, key)
+		if g.stale(call) {
+			g.revalidate(ctx, key, call, fn)
+		}
+		return g.hooks.share(call.value), call.info(), g.opts.sharedErr(call.err, call.waiters)
+	}
+	if loaded && ctx.Value(call) != nil {
+		var zero V
+		return zero, Info{}, ErrReentrant
+	}
+	if loaded && co.noWait {
+		var zero V
+		return zero, Info{}, errWouldBlock
+	}
+	if loaded && g.replaces(ctx, call, co) {
+		// Replace the call by a fresh one, led by this caller.
+		fresh := newCall[V](&g.opts)
+		if !g.store().CompareAndSwap(key, call, fresh) {
+			return g.join(ctx, key, fn, co)
+		}
+		if call.cached() {
+			g.evicted(key, call, Replaced)
+		}
+		call, loaded = fresh, false
+	}
+	if co.memoize {
+		call.setMemoize()
+	}
+	if !loaded {
+		if g.opts.background != nil {
+			return g.delegate(ctx, key, call, fn, co)
+		}
+		defer call.leave()
+		defer call.merged.watch(ctx)()
+		return g.lead(ctx, key, call, fn, co, false)
+	}
+	if !call.join(true, co.priority, g.opts.maxWaiters) {
+		var zero V
+		return zero, Info{}, ErrTooManyWaiters
+	}
+	defer call.leave()
+	g.opts.sync.joined()
+	stop, ok := call.merged.add(ctx)
+	if !ok {
+		// All the callers of the call are gone, its function is being
+		// canceled: start a new call instead.
+		g.store().CompareAndDelete(key, call)
+		return g.join(ctx, key, fn, co)
+	}
+	defer stop()
+	g.stats.shared.Add(1)
+	g.hooks.onJoin(ctx, key)
+	return g.wait(ctx, key, call, fn, co)
+}
+
+// delegate submits the execution of fn as the leader of call, created by the
+// caller, to the background (see [WithBackgroundLeader]), then waits for its
+// result like a caller joining the call.
+func (g *Group[K, V]) delegate(ctx context.Context, key K, call *call[V], fn func(context.Context) (V, error), co callOptions) (V, Info, error) {
+	// The background leader takes over the registration of the caller,
+	// which joins the call as a waiter. The waiter is not eligible for
+	// promotion, since the background leader never steps down.
+	call.join(false, 0, 0)
+	defer call.leave()
+	defer call.merged.watch(ctx)()
+	lctx := context.WithoutCancel(ctx)
+	lco := co
+	lco.delegated = true
+	g.opts.background(func() {
+		defer call.leave()
+		// The panic is propagated to the callers of the call instead.
+		defer func() { _ = recover() }()
+		g.lead(lctx, key, call, fn, lco, false)
+	})
+	g.stats.shared.Add(1)
+	g.hooks.onJoin(ctx, key)
+	return g.wait(ctx, key, call, nil, co)
+}
+
+// replaces reports whether a caller configured with co starts a fresh call
+// instead of joining call, or using its cached result, see [WithJoinPolicy],
+// [SkipCache] and [NoJoin].
+func (g *Group[K, V]) replaces(ctx context.Context, call *call[V], co callOptions) bool {
+	switch {
+	case call.cached():
+		return co.skipCache
+	case co.noJoin:
+		return true
+	default:
+		return g.opts.joinPolicy != nil && !g.opts.joinPolicy(g.opts.now().Sub(call.started), ctx)
+	}
+}
+
+// lead executes fn with ctx as the leader of call, then completes the call
+// with its result, unless the leader steps down (see [call.stepDown]).
+// promoted reports whether the leader was promoted from a waiter.
+//
+// fn is not executed if its context is already done: the leader steps down
+// right away, or completes the call with the context error if no waiter can
+// take over.
+func (g *Group[K, V]) lead(ctx context.Context, key K, call *call[V], fn func(context.Context) (V, error), co callOptions, promoted bool) (V, Info, error) {
+	fctx := ctx
+	if call.merged != nil {
+		var cancel context.CancelFunc
+		fctx, cancel = call.merged.context(ctx)
+		defer cancel()
+	}
+	if d := g.opts.callTimeout; d > 0 {
+		var cancel context.CancelFunc
+		fctx, cancel = context.WithTimeout(fctx, d)
+		defer cancel()
+	}
+	if !co.ctxless {
+		// Only a function receiving its context can be canceled, see
+		// [Group.Cancel].
+		var cancel context.CancelFunc
+		fctx, cancel = context.WithCancel(fctx)
+		defer cancel()
+		call.setCancel(cancel)
+		// Mark the context of fn, so that it can't join its own call.
+		fctx = context.WithValue(fctx, call, struct{}{})
+	}
+	if err := fctx.Err(); err != nil {
+		var zero V
+		if call.merged == nil && call.stepDown(ctx, err) {
+			return zero, Info{Shared: true, Leader: !promoted}, err
+		}
+		value := zero
+		if g.finish(key, call, zero, err, nil) {
+			value, err = call.value, call.err
+		} else {
+			err = ErrFlushed
+		}
+		return value, Info{Shared: promoted || call.waiters > 0, Leader: !promoted, Waiters: call.waiters}, err
+	}
+	g.stats.executions.Add(1)
+	g.running.start()
+	defer g.running.stop()
+	done := g.hooks.onStart(fctx, key)
+	g.opts.sync.await(call.callers.Load)
+	if co.waiters != nil {
+		*co.waiters = max(call.callers.Load()-1, 0)
+	}
+	start := time.Now()
+	var (
+		value       V
+		err         error
+		info        = Info{Shared: promoted, Leader: !promoted}
+		steppedDown bool
+	)
+	execute(fctx, &g.opts, g.wrap(key, fn, co.validated), func(v V, e error, p any) {
+		d := time.Since(start)
+		g.stats.failed(e, p)
+		if p == nil && call.merged == nil && call.stepDown(ctx, e) {
+			steppedDown = true
+			g.hooks.onFinish(done, key, true, e, d)
+			return
+		}
+		if p == nil {
+			e = call.aggregate(e)
+		}
+		callers := call.callers.Load()
+		if call.orphaned() {
+			g.stats.orphans.Add(1)
+		}
+		call.elapsed = d
+		call.deadline = deadline(fctx, call.merged)
+		published := g.finish(key, call, v, e, p)
+		if p != nil {
+			g.hooks.onPanicked(done, p, d)
+			return
+		}
+		value, err = call.value, call.err
+		if !published {
+			var zero V
+			value, err, e = zero, ErrFlushed, ErrFlushed
+		}
+		info.Shared = info.Shared || callers > 1
+		info.Waiters = call.waiters
+		info.Duration = d
+		info.LeaderChanges = call.changes
+		info.Deadline = call.deadline
+		if info.Shared && !co.delegated {
+			g.stats.shared.Add(1)
+		}
+		g.hooks.onFinish(done, key, info.Shared, e, d)
+	})
+	if steppedDown {
+		var zero V
+		return zero, Info{Shared: true, Leader: !promoted}, ctx.Err()
+	}
+	return value, info, err
+}
+
+// deadline returns the deadline of fctx, the context of the function of a
+// call, accounting for the deadlines of its callers if it is merged.
+func deadline(fctx context.Context, merged *mergedContext) time.Time {
+	d, _ := fctx.Deadline()
+	if merged == nil {
+		return d
+	}
+	if md := merged.deadline(); !md.IsZero() && (d.IsZero() || md.Before(d)) {
+		return md
+	}
+	return d
+}
+
+// wait waits for call to complete, or for ctx to be done, whichever happens
+// first. A caller giving up on ctx gets the zero value and ctx.Err().
+//
+// If fn is not nil, the caller is promoted to leader and executes it if the
+// leadership of the call becomes vacant.
+func (g *Group[K, V]) wait(ctx context.Context, key K, call *call[V], fn func(context.Context) (V, error), co callOptions) (V, Info, error) {
+	for {
+		var vacant <-chan struct{}
+		if fn != nil {
+			vacant = call.vacancy(co.priority)
+		}
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			// A result already published wins over a simultaneous
+			// cancellation, so that completed work is never wasted.
+			if !call.completed() {
+				call.departures.Add(1)
+				if fn != nil {
+					if lastErr, last := call.abandon(co.priority); last {
+						var zero V
+						g.finish(key, call, zero, lastErr, nil)
+					}
+				}
+				var zero V
+				return zero, Info{Shared: true}, ctx.Err()
+			}
+		case <-vacant:
+			if ctx.Err() == nil && call.promote(co.priority) {
+				return g.lead(ctx, key, call, fn, co, true)
+			}
+			continue
+		}
+		if call.abandoned && fn != nil {
+			// The call was abandoned by the other waiters right when this
+			// caller joined it, its result is not meant for it.
+			if ctx.Err() == nil {
+				return g.join(ctx, key, fn, co)
+			}
+			var zero V
+			return zero, Info{Shared: true}, ctx.Err()
+		}
+		if fn != nil && g.opts.errorFailover && call.failed() && ctx.Err() == nil {
+			// Execute fn again rather than sharing the error of the leader.
+			return g.join(ctx, key, fn, co)
+		}
+		value, err := call.result()
+		return g.hooks.share(value), call.info(), g.opts.sharedErr(err, call.waiters)
 	}
-	value, callers, err := call.do()
-	shared := loaded || callers > 1
-	return value, shared, err
 }
 
-// Forget removes the key from the group's active call registry.
+// load returns the call for key, storing a new one if there is none.
+// The returned bool is false if the call was stored.
+// Expired cached results are evicted along the way.
+//
+// The call of a key already stored is loaded first, so that joining it or
+// using its cached result does not allocate a new call. Goroutines missing
+// the same key concurrently may each allocate one, only the first stored
+// wins and the others are discarded.
+//
+// If the group is disabled, load always returns a new call, which is neither
+// stored nor cached, see [WithDisabled].
+func (g *Group[K, V]) load(key K) (*call[V], bool) {
+	if g.opts.disabled {
+		call := newCall[V](&g.opts)
+		call.invalidate()
+		return call, false
+	}
+	if call, ok := g.store().Load(key); ok && !call.expired(g.opts.now()) {
+		return call, true
+	}
+	return g.loadOrStore(key, newCall[V](&g.opts))
+}
+
+// loadOrStore returns the call for key, storing call if there is none. The
+// returned bool is false if call was stored. Expired cached results are
+// evicted along the way.
+func (g *Group[K, V]) loadOrStore(key K, call *call[V]) (*call[V], bool) {
+	actual, loaded := g.store().LoadOrStore(key, call)
+	for loaded && actual.expired(g.opts.now()) {
+		if g.store().CompareAndDelete(key, actual) {
+			g.evicted(key, actual, Expired)
+		}
+		actual, loaded = g.store().LoadOrStore(key, call)
+	}
+	return actual, loaded
+}
+
+// finish is called by the caller completing a call with its result. It owns
+// the deletion of the call, unless its result is cached.
+//
+// A result which is not cached, such as an error, is evicted before being
+// published to the waiters, so that a caller arriving once the call completed
+// never joins it and executes its function again.
+//
+// finish reports whether the result was published, which is not the case if
+// the call was flushed, see [Group.Flush].
+func (g *Group[K, V]) finish(key K, call *call[V], value V, err error, p any) bool {
+	if p == nil {
+		value, err = g.hooks.rescue(key, call, value, err)
+		g.hooks.admission(key, call, value, err)
+	}
+	cached, ok := call.complete(value, err, p)
+	switch {
+	case !ok:
+		return false
+	case cached:
+		g.cache(key, call)
+	case call.kept:
+		g.forgetAfter(key, call)
+	default:
+		g.store().CompareAndDelete(key, call)
+	}
+	call.publish()
+	return true
+}
+
+// forgetAfter forgets the result of call, stored for key, once the delay of
+// [WithAutoForget] elapses, unless it was removed or replaced.
+func (g *Group[K, V]) forgetAfter(key K, call *call[V]) {
+	time.AfterFunc(g.opts.autoForget, func() {
+		if g.store().CompareAndDelete(key, call) {
+			g.evicted(key, call, Expired)
+		}
+	})
+}
+
+// Forget removes the key from the group's active call registry, along with
+// its cached result, if any.
 // Future calls to [Group.Do] with this key will execute the function again,
 // rather than waiting for or sharing an in-flight call.
 //
@@ -78,4 +655,269 @@ func (g *Group[K, V]) Do(key K, fn func() (V, error)) (V, bool, error) {
 // will not join it.
 //
 // Forget is safe for concurrent use by multiple goroutines.
-func (g *Group[K, V]) Forget(key K) { g.m.LoadAndDelete(key) }
+func (g *Group[K, V]) Forget(key K) {
+	g.stats.forgets.Add(1)
+	if call, ok := g.store().LoadAndDelete(key); ok {
+		g.evicted(key, call, Forgotten)
+	}
+}
+
+// ForgetMany forgets every key of keys, as if [Group.Forget] was called for
+// each of them, and returns the number of keys which had an in-flight call
+// or a cached result, and were removed. This helps measuring the
+// effectiveness of an invalidation.
+//
+// ForgetMany is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) ForgetMany(keys []K) int {
+	g.stats.forgets.Add(int64(len(keys)))
+	n := 0
+	for _, key := range keys {
+		if call, ok := g.store().LoadAndDelete(key); ok {
+			g.evicted(key, call, Forgotten)
+			n++
+		}
+	}
+	return n
+}
+
+// Cancel cancels the call in flight for key, if any: the context passed to its
+// function is canceled, as well as the ones of the functions executed by the
+// callers promoted to leader afterwards. A function honoring its context thus
+// aborts, and the call completes with the error it returns, usually
+// [context.Canceled], which every caller of the call receives. Unlike
+// [Group.Forget], Cancel does not remove the key: new callers keep joining the
+// call until it completes.
+//
+// Cancel only affects the functions honoring their context, such as the ones
+// given to [Group.DoContext]: the functions given to [Group.Do] have no
+// context, and keep executing until they return. Cancel does nothing if key
+// has no call in flight.
+//
+// Cancel is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Cancel(key K) {
+	if call, ok := g.store().Load(key); ok {
+		call.abort()
+	}
+}
+
+// ForgetFunc forgets every key of the group for which pred returns true, as
+// if [Group.Forget] was called for each of them, for instance to invalidate
+// all the keys sharing a prefix. The calls in flight for the forgotten keys
+// keep serving their existing waiters, but new callers don't join them.
+//
+// Keys stored concurrently with ForgetFunc may or may not be forgotten.
+//
+// ForgetFunc is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) ForgetFunc(pred func(K) bool) {
+	for key := range g.store().Range {
+		if !pred(key) {
+			continue
+		}
+		g.stats.forgets.Add(1)
+		if call, ok := g.store().LoadAndDelete(key); ok {
+			g.evicted(key, call, Forgotten)
+		}
+	}
+}
+
+// Invalidate marks the current call for key as stale, as if it started before
+// a new logical time, and removes it from the group. A stale call still
+// returns its result to its existing waiters, but its result is never cached
+// (see [NewWithTTL]), and new callers start a fresh execution instead of
+// joining it. A cached result for key is removed.
+//
+// Unlike [Group.Forget], which only unregisters the key, Invalidate
+// coordinates with the cache layer: a stale result is not cached even if it
+// is still being computed. Once invalidated, the stale call is not registered
+// anymore, so [Group.ForgetUnshared] only considers the calls started after
+// Invalidate.
+//
+// Invalidate is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Invalidate(key K) {
+	if call, ok := g.store().LoadAndDelete(key); ok {
+		call.invalidate()
+		g.evicted(key, call, Forgotten)
+	}
+}
+
+// Len returns the number of keys that currently have an in-flight call or a
+// cached result.
+//
+// Since calls may start and complete concurrently, the returned value is
+// a point-in-time estimate which may already be stale when Len returns.
+//
+// Len is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Len() int {
+	n := 0
+	for range g.store().Range {
+		n++
+	}
+	return n
+}
+
+// Keys returns an iterator over the keys that currently have an in-flight
+// call or a cached result.
+//
+// The iterator reflects a loosely-consistent snapshot of the group: keys
+// whose call starts or completes during the iteration may or may not be
+// yielded. It is safe to call [Group.Do] while iterating.
+//
+// Keys is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range g.store().Range {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the keys that currently have an in-flight
+// call, with the number of callers executing or waiting on it, including its
+// leader, like [Group.Waiters]. Keys with a cached result and no call in
+// flight are not yielded.
+//
+// Like [Group.Keys], the iterator reflects a loosely-consistent snapshot of
+// the group: the counts may shift during the iteration, as callers join or
+// leave the calls.
+//
+// All is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) All() iter.Seq2[K, int32] {
+	return func(yield func(K, int32) bool) {
+		for key, call := range g.store().Range {
+			if call.completed() {
+				continue
+			}
+			if !yield(key, call.callers.Load()) {
+				return
+			}
+		}
+	}
+}
+
+// DoOnce is like [Group.Do], but a successful result is cached forever once
+// its call completes, so that fn runs at most once per key for the lifetime of
+// the group, like a concurrent memoizer. Errors are not cached, so that a
+// failed initialization can be retried.
+//
+// A call for key already in flight is joined, and its successful result is
+// cached forever as well. A result already cached with a TTL (see
+// [NewWithTTL]) is reused until it expires. Like other cached results,
+// a memoized result is removed by [Group.Forget].
+//
+// DoOnce is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoOnce(key K, fn func() (V, error)) (V, bool, error) {
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) { return fn() }, callOptions{memoize: true, ctxless: true})
+	return value, info.Shared, err
+}
+
+// TryDo is like [Group.Do], but never blocks on another call: if a call for
+// key is already in flight, TryDo returns immediately with ran=false instead
+// of joining it. Otherwise, TryDo executes fn synchronously as the leader of
+// a new call, or returns the cached result of key, with ran=true. This lets
+// latency-sensitive callers serve stale data rather than wait.
+//
+// TryDo is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) TryDo(key K, fn func() (V, error)) (value V, shared, ran bool, err error) {
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) { return fn() }, callOptions{noWait: true, ctxless: true})
+	if err == errWouldBlock {
+		return value, false, false, nil
+	}
+	return value, info.Shared, true, err
+}
+
+// Reset removes every key from the group, as if [Group.Forget] was called for
+// each of them. In-flight calls continue to execute and serve their existing
+// waiters, but new callers will not join them. Cached results are removed.
+//
+// Calls started concurrently with Reset may or may not be removed.
+//
+// Reset is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Reset() {
+	g.clear(Forgotten)
+}
+
+// clear removes every key from the group, reporting the evicted results with
+// reason.
+func (g *Group[K, V]) clear(reason EvictReason) {
+	for key, call := range g.store().Range {
+		if g.store().CompareAndDelete(key, call) {
+			g.evicted(key, call, reason)
+		}
+	}
+}
+
+// Flush removes every key from the group, like [Group.Reset], but also
+// completes every call in flight immediately with [ErrFlushed]: all their
+// callers, including their leader, get ErrFlushed instead of waiting for the
+// result. The function of a flushed call may still be running, but its result
+// is discarded once it returns. New callers start new calls, [Group.Close]
+// must be called first to also reject them, for instance during a controlled
+// shutdown.
+//
+// Flush is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Flush() {
+	for key, call := range g.store().Range {
+		if g.store().CompareAndDelete(key, call) && !call.flush() {
+			g.evicted(key, call, Forgotten)
+		}
+	}
+}
+
+// ForgetUnshared is like [Group.Forget], but only removes the key if its call
+// is not shared, that is if at most one caller is currently executing or
+// waiting on it. It reports whether the key was removed, an unknown key is
+// not removed.
+//
+// Callers may still join the call between the check and the removal, it is
+// only meant to avoid yanking a key out from under a large set of active
+// waiters.
+//
+// ForgetUnshared is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) ForgetUnshared(key K) bool {
+	call, ok := g.store().Load(key)
+	if !ok || call.callers.Load() > 1 {
+		return false
+	}
+	if !g.store().CompareAndDelete(key, call) {
+		return false
+	}
+	g.evicted(key, call, Forgotten)
+	return true
+}
+
+// InFlight reports whether key has an active call which has not completed
+// yet. Unlike [Group.Peek], it ignores cached results. This is useful for
+// admission decisions, such as serving stale data rather than joining a call
+// already in flight.
+//
+// Since calls may start and complete concurrently, the returned value may
+// already be stale when InFlight returns.
+//
+// InFlight is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) InFlight(key K) bool {
+	call, ok := g.store().Load(key)
+	return ok && !call.completed()
+}
+
+// Close marks the group as closed: every future call to the group returns
+// [ErrClosed] immediately, without executing its function nor joining an
+// in-flight call. Calls already in flight are allowed to finish and return
+// their result to their existing waiters, which makes Close suitable to
+// drain a group during a service shutdown. Close also removes the cached
+// results and stops the janitor of the group, see [WithJanitor].
+//
+// Close is idempotent and always returns nil.
+//
+// Close is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Close() error {
+	if g.closed.Swap(true) {
+		return nil
+	}
+	if g.stop != nil {
+		close(g.stop)
+	}
+	g.clear(Closed)
+	return nil
+}
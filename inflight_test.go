@@ -1,8 +1,13 @@
 package inflight
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -33,9 +38,9 @@ func TestDoErr(t *testing.T) {
 }
 
 func TestDoDupSuppress(t *testing.T) {
-	var g Group[string, string]
-
 	const n = 16
+	// Hold the leader until all the other callers joined its call.
+	g := New[string, string](WithSynchronizer(NewSynchronizer(n - 1)))
 
 	var nbCalls atomic.Int32
 	var nbShared atomic.Int32
@@ -47,7 +52,6 @@ func TestDoDupSuppress(t *testing.T) {
 		wg.Go(func() {
 			v, shared, err := g.Do("key", func() (string, error) {
 				nbCalls.Add(1)
-				time.Sleep(10 * time.Millisecond)
 				return "bar", nil
 			})
 			require.NoError(t, err)
@@ -62,11 +66,7 @@ func TestDoDupSuppress(t *testing.T) {
 	close(c)
 
 	require.Equal(t, int32(1), nbCalls.Load())
-
-	// At least n-1 calls should be marked as shared.
-	require.GreaterOrEqual(t, nbShared.Load(), int32(n-1))
-
-	t.Logf("%d calls were shared", nbShared.Load())
+	require.Equal(t, int32(n), nbShared.Load())
 
 	for v := range c {
 		require.Equal(t, "bar", v)
@@ -174,3 +174,2034 @@ func TestSharedFlag(t *testing.T) {
 		t.Logf("second call shared=%v", shared2)
 	})
 }
+
+func TestDoContext(t *testing.T) {
+	var g Group[string, string]
+	type ctxKey struct{}
+	ctx := context.WithValue(t.Context(), ctxKey{}, "leader")
+	v, shared, err := g.DoContext(ctx, "key", func(ctx context.Context) (string, error) {
+		return ctx.Value(ctxKey{}).(string), nil
+	})
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "leader", v)
+}
+
+func TestDoContextWaiterCancel(t *testing.T) {
+	var g Group[string, string]
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		v, _, err := g.DoContext(t.Context(), "key", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "bar", ctx.Err()
+		})
+		require.NoError(t, err)
+		require.Equal(t, "bar", v)
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	v, shared, err := g.DoContext(ctx, "key", func(context.Context) (string, error) {
+		t.Error("waiter must not execute fn")
+		return "", nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.True(t, shared)
+	require.Empty(t, v)
+
+	// The in-flight call is still joinable and running for the others.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	v, shared, err = g.DoContext(t.Context(), "key", func(context.Context) (string, error) {
+		t.Error("waiter must not execute fn")
+		return "", nil
+	})
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, "bar", v)
+	<-leaderDone
+}
+
+func TestDoContextLeaderPromotion(t *testing.T) {
+	var g Group[string, string]
+
+	leaderCtx, cancelLeader := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		v, shared, err := g.DoContext(leaderCtx, "key", func(ctx context.Context) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.True(t, shared)
+		require.Empty(t, v)
+	}()
+	<-started
+
+	waiterDone := make(chan struct{})
+	go func() {
+		defer close(waiterDone)
+		v, shared, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) {
+			return "promoted", nil
+		})
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "promoted", v)
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the waiter joined.
+
+	// Canceling the leader promotes the waiter instead of propagating the
+	// cancellation to it.
+	cancelLeader()
+	<-leaderDone
+	<-waiterDone
+	require.Zero(t, g.Len())
+}
+
+func TestDoContextCanceledLeader(t *testing.T) {
+	var g Group[string, string]
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	v, shared, err := g.DoContext(ctx, "key", func(context.Context) (string, error) {
+		panic("unreachable")
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, shared)
+	require.Empty(t, v)
+	require.Zero(t, g.Len())
+	require.Zero(t, g.Stats().Executions)
+
+	// A healthy caller leads the next call.
+	v, shared, err = g.DoContext(t.Context(), "key", func(context.Context) (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "bar", v)
+}
+
+func TestDoContextAllCanceled(t *testing.T) {
+	var g Group[string, string]
+
+	var execs atomic.Int32
+	fn := func(ctx context.Context) (string, error) {
+		execs.Add(1)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	const n = 4
+	cancels := make([]context.CancelFunc, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancels[i] = cancel
+		wg.Go(func() {
+			_, _, err := g.DoContext(ctx, "key", fn)
+			require.ErrorIs(t, err, context.Canceled)
+		})
+		time.Sleep(5 * time.Millisecond) // Ensure the first caller leads.
+	}
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	wg.Wait()
+	require.Zero(t, g.Len())
+	require.LessOrEqual(t, execs.Load(), int32(n))
+
+	// The key is usable again.
+	v, _, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+}
+
+func TestDoContextLeaderPromotionStress(t *testing.T) {
+	var g Group[int, int]
+
+	const (
+		keys    = 4
+		callers = 64
+	)
+	var wg sync.WaitGroup
+	for i := range callers {
+		key := i % keys
+		canceled := rand.N(2) == 0
+		wg.Go(func() {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			if canceled {
+				time.AfterFunc(rand.N(5*time.Millisecond), cancel)
+			}
+			v, _, err := g.DoContext(ctx, key, func(ctx context.Context) (int, error) {
+				select {
+				case <-time.After(rand.N(5 * time.Millisecond)):
+					return key, nil
+				case <-ctx.Done():
+					return 0, ctx.Err()
+				}
+			})
+			if err != nil {
+				// Only a caller whose context is done gives up.
+				require.ErrorIs(t, err, context.Canceled)
+				require.True(t, canceled)
+				return
+			}
+			require.Equal(t, key, v)
+		})
+	}
+	wg.Wait()
+	require.Zero(t, g.Len())
+}
+
+func TestDoChan(t *testing.T) {
+	var g Group[string, string]
+
+	block := make(chan struct{})
+	var calls atomic.Int32
+	fn := func() (string, error) {
+		calls.Add(1)
+		<-block
+		return "bar", nil
+	}
+
+	const n = 8
+	chans := make([]<-chan Result[string], n)
+	chans[0] = g.DoChan("key", fn)
+	time.Sleep(10 * time.Millisecond) // Ensure the first call is in flight.
+	for i := 1; i < n; i++ {
+		chans[i] = g.DoChan("key", fn)
+	}
+
+	// Nothing is delivered while the call is blocked, so the client-side
+	// timeout fires first.
+	select {
+	case res := <-chans[0]:
+		t.Fatalf("unexpected result %+v", res)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(block)
+	for _, ch := range chans {
+		select {
+		case res := <-ch:
+			require.NoError(t, res.Err)
+			require.Equal(t, "bar", res.Val)
+			require.True(t, res.Shared)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the result")
+		}
+	}
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestLen(t *testing.T) {
+	var g Group[string, string]
+	require.Zero(t, g.Len())
+
+	const n = 8
+	block := make(chan struct{})
+	var started, wg sync.WaitGroup
+	started.Add(n)
+	for i := range n {
+		wg.Go(func() {
+			g.Do(fmt.Sprintf("key%d", i), func() (string, error) {
+				started.Done()
+				<-block
+				return "", nil
+			})
+		})
+	}
+
+	started.Wait()
+	require.Equal(t, n, g.Len())
+
+	close(block)
+	wg.Wait()
+	require.Zero(t, g.Len())
+}
+
+func TestKeys(t *testing.T) {
+	var g Group[string, string]
+	require.Empty(t, slices.Collect(g.Keys()))
+
+	keys := []string{"a", "b", "c", "d"}
+	block := make(chan struct{})
+	var started, wg sync.WaitGroup
+	started.Add(len(keys))
+	for _, k := range keys {
+		wg.Go(func() {
+			g.Do(k, func() (string, error) {
+				started.Done()
+				<-block
+				return "", nil
+			})
+		})
+	}
+
+	started.Wait()
+	require.ElementsMatch(t, keys, slices.Collect(g.Keys()))
+
+	close(block)
+	wg.Wait()
+	require.Empty(t, slices.Collect(g.Keys()))
+}
+
+func TestAll(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	require.Empty(t, maps.Collect(g.All()))
+
+	callers := map[string]int32{"a": 1, "b": 2, "c": 3}
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for k, n := range callers {
+		for range n {
+			wg.Go(func() {
+				g.Do(k, func() (string, error) {
+					<-block
+					return "", nil
+				})
+			})
+		}
+	}
+	require.Eventually(t, func() bool {
+		return maps.Equal(callers, maps.Collect(g.All()))
+	}, time.Second, time.Millisecond)
+
+	// Stopping early.
+	for range g.All() {
+		break
+	}
+
+	// Cached results are not in flight.
+	close(block)
+	wg.Wait()
+	require.Equal(t, 3, g.Len())
+	require.Empty(t, maps.Collect(g.All()))
+}
+
+func TestNewWithTTL(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+
+	t.Run("hit", func(t *testing.T) {
+		g := NewWithTTL[string, int](ttl)
+		var calls atomic.Int32
+		fn := func() (int, error) { return int(calls.Add(1)), nil }
+
+		v, shared, err := g.Do("key", fn)
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, 1, v)
+
+		v, shared, err = g.Do("key", fn)
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, 1, v)
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		g := NewWithTTL[string, int](ttl)
+		var calls atomic.Int32
+		fn := func() (int, error) { return int(calls.Add(1)), nil }
+
+		g.Do("key", fn)
+		time.Sleep(2 * ttl)
+
+		v, shared, err := g.Do("key", fn)
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, 2, v)
+	})
+
+	t.Run("errors_not_cached", func(t *testing.T) {
+		g := NewWithTTL[string, int](ttl)
+		var calls atomic.Int32
+		fn := func() (int, error) {
+			calls.Add(1)
+			return 0, errors.New("some error")
+		}
+
+		g.Do("key", fn)
+		_, shared, err := g.Do("key", fn)
+		require.Error(t, err)
+		require.False(t, shared)
+		require.Equal(t, int32(2), calls.Load())
+		require.Zero(t, g.Len())
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		g := NewWithTTL[string, int](ttl)
+		var calls atomic.Int32
+		fn := func() (int, error) { return int(calls.Add(1)), nil }
+
+		g.Do("key", fn)
+		require.Equal(t, 1, g.Len())
+		g.Forget("key")
+		require.Zero(t, g.Len())
+
+		v, shared, err := g.Do("key", fn)
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, 2, v)
+	})
+}
+
+func TestWithErrorTTL(t *testing.T) {
+	const errTTL = 50 * time.Millisecond
+	someErr := errors.New("some error")
+
+	t.Run("cached", func(t *testing.T) {
+		g := NewWithTTL[string, int](time.Minute, WithErrorTTL(errTTL))
+		var calls atomic.Int32
+		fn := func() (int, error) {
+			calls.Add(1)
+			return 0, someErr
+		}
+
+		_, shared, err := g.Do("key", fn)
+		require.ErrorIs(t, err, someErr)
+		require.False(t, shared)
+
+		for range 4 {
+			_, shared, err = g.Do("key", fn)
+			require.ErrorIs(t, err, someErr)
+			require.True(t, shared)
+		}
+		require.Equal(t, int32(1), calls.Load())
+
+		time.Sleep(2 * errTTL)
+		_, shared, err = g.Do("key", fn)
+		require.ErrorIs(t, err, someErr)
+		require.False(t, shared)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("context_errors_not_cached", func(t *testing.T) {
+		g := New[string, int](WithErrorTTL(time.Minute))
+		var calls atomic.Int32
+		fn := func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			return 0, ctx.Err()
+		}
+
+		ctx, cancel := context.WithCancel(t.Context())
+		g.DoContext(ctx, "key", func(ctx context.Context) (int, error) {
+			cancel()
+			return fn(ctx)
+		})
+		_, shared, err := g.DoContext(t.Context(), "key", fn)
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestDoPanic(t *testing.T) {
+	var g Group[string, string]
+	require.PanicsWithValue(t, "boom", func() {
+		g.Do("key", func() (string, error) { panic("boom") })
+	})
+	require.Zero(t, g.Len())
+}
+
+func TestWithPanicToError(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute, WithPanicToError(), WithErrorTTL(time.Minute))
+
+	block := make(chan struct{})
+	const n = 8
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, shared, err := g.Do("key", func() (string, error) {
+				<-block
+				panic("boom")
+			})
+			var perr *PanicError
+			require.ErrorAs(t, err, &perr)
+			require.Equal(t, "boom", perr.Value)
+			require.NotEmpty(t, perr.Stack)
+			require.True(t, shared)
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+
+	// The key was removed, a later call executes again.
+	require.Zero(t, g.Len())
+	v, shared, err := g.Do("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "bar", v)
+}
+
+func TestForgetUnshared(t *testing.T) {
+	var g Group[string, string]
+	require.False(t, g.ForgetUnshared("unknown"))
+
+	block := make(chan struct{})
+	var started, wg sync.WaitGroup
+	started.Add(1)
+	fn := func() (string, error) {
+		started.Done()
+		<-block
+		return "", nil
+	}
+
+	// An idle key, only executed by its leader.
+	wg.Go(func() { g.Do("idle", fn) })
+	started.Wait()
+
+	// A shared key, with several blocked waiters.
+	started.Add(1)
+	for range 4 {
+		wg.Go(func() { g.Do("shared", fn) })
+	}
+	started.Wait()
+	time.Sleep(10 * time.Millisecond) // Ensure all goroutines are waiting.
+
+	require.False(t, g.ForgetUnshared("shared"))
+	require.True(t, g.ForgetUnshared("idle"))
+	require.ElementsMatch(t, []string{"shared"}, slices.Collect(g.Keys()))
+
+	close(block)
+	wg.Wait()
+}
+
+func TestForgetFunc(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		mustDo(t, g, key, key)
+	}
+
+	// An in-flight call for a matching key keeps serving its waiter.
+	block := make(chan struct{})
+	fn := func() (string, error) {
+		<-block
+		return "user:3", nil
+	}
+	leader := g.DoChan("user:3", fn)
+	require.Eventually(t, func() bool { return g.InFlight("user:3") }, time.Second, time.Millisecond)
+	waiter := g.DoChan("user:3", fn)
+	time.Sleep(10 * time.Millisecond) // Ensure the waiter joined.
+
+	g.ForgetFunc(func(key string) bool { return strings.HasPrefix(key, "user:") })
+	require.Equal(t, []string{"order:1"}, slices.Collect(g.Keys()))
+	require.False(t, g.InFlight("user:3"))
+	close(block)
+	require.Equal(t, Result[string]{Val: "user:3", Shared: true, Waiters: 1}, <-leader)
+	require.Equal(t, Result[string]{Val: "user:3", Shared: true, Waiters: 1}, <-waiter)
+	require.Equal(t, []string{"order:1"}, slices.Collect(g.Keys()))
+}
+
+func TestWithMaxWaiters(t *testing.T) {
+	const maxWaiters = 3
+	g := New[string, string](WithMaxWaiters(maxWaiters))
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, _, err := g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "bar", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "bar", v)
+	})
+	<-started
+
+	const n = 10
+	var nbRejected atomic.Int32
+	for range n {
+		wg.Go(func() {
+			v, _, err := g.Do("key", func() (string, error) { return "", nil })
+			if errors.Is(err, ErrTooManyWaiters) {
+				nbRejected.Add(1)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "bar", v)
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting or rejected.
+	require.Equal(t, int32(n-maxWaiters), nbRejected.Load())
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(n-maxWaiters), nbRejected.Load())
+}
+
+func TestDoOnce(t *testing.T) {
+	var g Group[string, int]
+
+	var calls atomic.Int32
+	fn := func() (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return int(calls.Add(1)), nil
+	}
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Go(func() {
+			v, _, err := g.DoOnce("key", fn)
+			require.NoError(t, err)
+			require.Equal(t, 1, v)
+		})
+	}
+	wg.Wait()
+
+	for range 16 {
+		v, shared, err := g.DoOnce("key", fn)
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, 1, v)
+	}
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestDoOnceErr(t *testing.T) {
+	var g Group[string, int]
+
+	var calls atomic.Int32
+	someErr := errors.New("some error")
+	_, _, err := g.DoOnce("key", func() (int, error) {
+		calls.Add(1)
+		return 0, someErr
+	})
+	require.ErrorIs(t, err, someErr)
+
+	// A failed initialization is retried.
+	v, shared, err := g.DoOnce("key", func() (int, error) {
+		calls.Add(1)
+		return 42, nil
+	})
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, 42, v)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestClose(t *testing.T) {
+	var g Group[string, string]
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, shared, err := g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "bar", nil
+		})
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "bar", v)
+	})
+	<-started
+	wg.Go(func() {
+		v, shared, err := g.Do("key", func() (string, error) { return "", nil })
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "bar", v)
+	})
+	time.Sleep(10 * time.Millisecond) // Ensure the waiter joined.
+
+	require.NoError(t, g.Close())
+	require.NoError(t, g.Close(), "Close must be idempotent")
+
+	// Calls after Close fail fast, even for a key in flight.
+	for _, key := range []string{"key", "other"} {
+		_, shared, err := g.Do(key, func() (string, error) {
+			t.Error("fn must not be executed after Close")
+			return "", nil
+		})
+		require.ErrorIs(t, err, ErrClosed)
+		require.False(t, shared)
+	}
+	res := <-g.DoChan("key", func() (string, error) { return "", nil })
+	require.ErrorIs(t, res.Err, ErrClosed)
+
+	close(block)
+	wg.Wait()
+}
+
+func TestWithCallTimeout(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute, WithCallTimeout(20*time.Millisecond))
+
+	slow := func(ctx context.Context) (string, error) {
+		select {
+		case <-time.After(time.Second):
+			return "slow", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			_, _, err := g.DoContext(t.Context(), "key", slow)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		})
+	}
+	wg.Wait()
+
+	// The timed-out key was evicted, a later call executes again.
+	require.Zero(t, g.Len())
+	v, shared, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) { return "fast", nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "fast", v)
+}
+
+func TestInvalidate(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, _, err := g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "stale", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "stale", v, "a stale call still returns its result")
+	})
+	<-started
+
+	g.Invalidate("key")
+	close(block)
+	wg.Wait()
+
+	// The stale result was not cached, the next call executes again.
+	v, shared, err := g.Do("key", func() (string, error) { return "fresh", nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "fresh", v)
+
+	// Invalidate also removes cached results.
+	g.Invalidate("key")
+	require.Zero(t, g.Len())
+}
+
+func TestReset(t *testing.T) {
+	var g Group[string, string]
+
+	g.DoOnce("memoized", func() (string, error) { return "old", nil })
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, _, err := g.Do("inflight", func() (string, error) {
+			close(started)
+			<-block
+			return "old", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "old", v)
+	})
+	<-started
+
+	g.Reset()
+	require.Zero(t, g.Len())
+
+	for _, key := range []string{"memoized", "inflight"} {
+		v, shared, err := g.Do(key, func() (string, error) { return "new", nil })
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, "new", v)
+	}
+
+	close(block)
+	wg.Wait()
+}
+
+func TestWithWaitHistogram(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		durations []time.Duration
+	)
+	g := New[string, string](WithWaitHistogram(func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		durations = append(durations, d)
+	}))
+
+	const (
+		n     = 8
+		sleep = 20 * time.Millisecond
+	)
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		g.Do("key", func() (string, error) {
+			close(started)
+			time.Sleep(sleep)
+			return "", nil
+		})
+	})
+	<-started
+	for range n - 1 {
+		wg.Go(func() { g.Do("key", func() (string, error) { return "", nil }) })
+	}
+	wg.Wait()
+
+	require.Len(t, durations, n)
+	// The leader waited at least for the execution of its function.
+	require.GreaterOrEqual(t, slices.Max(durations), sleep)
+}
+
+func TestWithWaitHistogramFreshMulti(t *testing.T) {
+	var observed atomic.Int32
+	g := New[string, string](WithWaitHistogram(func(time.Duration) { observed.Add(1) }))
+
+	g.DoFresh("key", func() (string, error) { return "", nil })
+	require.Equal(t, int32(1), observed.Load())
+
+	g.DoMulti([]string{"a", "b", "c"}, func(missing []string) (map[string]string, error) {
+		return map[string]string{"a": "1", "b": "2", "c": "3"}, nil
+	})
+	require.Equal(t, int32(4), observed.Load()) // One per key.
+}
+
+func TestDoErrEagerEviction(t *testing.T) {
+	g := NewWithTTL[string, int](time.Minute)
+	someErr := errors.New("some error")
+
+	// Concurrent callers keep hammering the key to widen the race window.
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for ctx.Err() == nil {
+				g.Peek("key")
+			}
+		})
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	const n = 1000
+	var calls atomic.Int32
+	for i := range n {
+		g.Forget("key") // Drop the successful results cached along the way.
+		_, _, err := g.Do("key", func() (int, error) {
+			calls.Add(1)
+			if i%2 == 1 {
+				return 0, someErr
+			}
+			return i, nil
+		})
+		if i%2 == 1 {
+			require.ErrorIs(t, err, someErr)
+			// The very next call always executes again.
+			v, shared, err := g.Do("key", func() (int, error) {
+				calls.Add(1)
+				return i, nil
+			})
+			require.NoError(t, err)
+			require.False(t, shared)
+			require.Equal(t, i, v)
+		}
+	}
+	require.Equal(t, int32(n+n/2), calls.Load())
+}
+
+func TestWithMaxConcurrency(t *testing.T) {
+	const n = 3
+	g := New[int, int](WithMaxConcurrency(n))
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			key := i % 10
+			v, _, err := g.Do(key, func() (int, error) {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := maxRunning.Load()
+					if cur <= old || maxRunning.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return key, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, key, v)
+		})
+	}
+	wg.Wait()
+	require.LessOrEqual(t, maxRunning.Load(), int32(n))
+	require.Positive(t, maxRunning.Load())
+}
+
+func TestWithMaxConcurrencyContext(t *testing.T) {
+	g := New[string, string](WithMaxConcurrency(1))
+
+	release := make(chan struct{})
+	go g.Do("busy", func() (string, error) {
+		<-release
+		return "busy", nil
+	})
+	time.Sleep(10 * time.Millisecond) // Let the first call hold the only slot.
+
+	// A waiter joining the busy call doesn't need a slot.
+	go g.Do("busy", func() (string, error) { panic("unreachable") })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	var calls atomic.Int32
+	_, _, err := g.DoContext(ctx, "key", func(context.Context) (string, error) {
+		calls.Add(1)
+		return "bar", nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, calls.Load())
+
+	close(release)
+	v, _, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) {
+		calls.Add(1)
+		return "bar", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestInFlight(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	require.False(t, g.InFlight("key"))
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "bar", nil
+		})
+	}()
+	<-started
+	require.True(t, g.InFlight("key"))
+	require.False(t, g.InFlight("other"))
+
+	close(block)
+	<-done
+	// The result is cached, but no longer in flight.
+	require.False(t, g.InFlight("key"))
+	_, ok := g.Peek("key")
+	require.True(t, ok)
+}
+
+func TestTryDo(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+
+	// Uncontended: the caller leads the call.
+	v, shared, ran, err := g.TryDo("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.False(t, shared)
+	require.Equal(t, "bar", v)
+
+	// Cached: the result is returned right away.
+	v, shared, ran, err = g.TryDo("key", func() (string, error) { panic("unreachable") })
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.True(t, shared)
+	require.Equal(t, "bar", v)
+}
+
+func TestTryDoContended(t *testing.T) {
+	var g Group[string, string]
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "bar", nil
+		})
+	}()
+	<-started
+
+	v, shared, ran, err := g.TryDo("key", func() (string, error) { panic("unreachable") })
+	require.NoError(t, err)
+	require.False(t, ran)
+	require.False(t, shared)
+	require.Empty(t, v)
+
+	close(block)
+	<-done
+	v, _, ran, err = g.TryDo("key", func() (string, error) { return "baz", nil })
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.Equal(t, "baz", v)
+	require.Equal(t, Stats{Calls: 3, Executions: 2}, g.Stats())
+}
+
+func TestDoContextCanceledWaitersNotCounted(t *testing.T) {
+	for _, survivors := range []int{0, 1, 3} {
+		t.Run(fmt.Sprint(survivors), func(t *testing.T) {
+			var g Group[string, string]
+			const quitters = 4
+
+			block := make(chan struct{})
+			type result struct {
+				info Info
+				err  error
+			}
+			leader := make(chan result, 1)
+			go func() {
+				_, info, err := g.DoDetailed("key", func() (string, error) {
+					<-block
+					return "bar", nil
+				})
+				leader <- result{info, err}
+			}()
+			time.Sleep(10 * time.Millisecond) // Let the leader start.
+
+			ctx, cancel := context.WithCancel(t.Context())
+			var wg sync.WaitGroup
+			for range quitters {
+				wg.Go(func() {
+					_, _, err := g.DoContext(ctx, "key", func(context.Context) (string, error) {
+						panic("unreachable")
+					})
+					require.ErrorIs(t, err, context.Canceled)
+				})
+			}
+			var stayed sync.WaitGroup
+			for range survivors {
+				stayed.Go(func() {
+					v, shared, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) {
+						panic("unreachable")
+					})
+					require.NoError(t, err)
+					require.True(t, shared)
+					require.Equal(t, "bar", v)
+				})
+			}
+			time.Sleep(10 * time.Millisecond) // Let the waiters join.
+			cancel()
+			wg.Wait()
+
+			close(block)
+			stayed.Wait()
+			r := <-leader
+			require.NoError(t, r.err)
+			require.Equal(t, survivors > 0, r.info.Shared)
+			require.Equal(t, int32(survivors), r.info.Waiters)
+		})
+	}
+}
+
+func TestWithErrorWrap(t *testing.T) {
+	g := New[string, string](WithErrorWrap(), WithErrorTTL(time.Minute))
+	someErr := errors.New("some error")
+
+	const n = 4
+	block := make(chan struct{})
+	var leaders, followers atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, info, err := g.DoDetailed("key", func() (string, error) {
+				<-block
+				return "", someErr
+			})
+			require.ErrorIs(t, err, someErr)
+			var shared *SharedError
+			if info.Leader {
+				leaders.Add(1)
+				require.Equal(t, someErr, err)
+				require.False(t, errors.As(err, &shared))
+				return
+			}
+			followers.Add(1)
+			require.ErrorAs(t, err, &shared)
+			require.Equal(t, someErr, shared.Err)
+			require.Equal(t, int32(n-1), shared.Waiters)
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), leaders.Load())
+	require.Equal(t, int32(n-1), followers.Load())
+
+	// A cached error is shared as well.
+	_, _, err := g.Do("key", func() (string, error) { panic("unreachable") })
+	var shared *SharedError
+	require.ErrorAs(t, err, &shared)
+	require.ErrorIs(t, err, someErr)
+
+	// Successes and context errors are not wrapped.
+	v, _, err := g.Do("other", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, _, err = g.DoContext(ctx, "key2", func(ctx context.Context) (string, error) { return "", ctx.Err() })
+	require.Equal(t, context.Canceled, err)
+}
+
+// gatedContext is a context whose first call to Done blocks until gate is
+// closed, to control when a caller starts waiting on it.
+type gatedContext struct {
+	context.Context
+	entered chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+}
+
+func (c *gatedContext) Done() <-chan struct{} {
+	c.once.Do(func() {
+		close(c.entered)
+		<-c.gate
+	})
+	return c.Context.Done()
+}
+
+func TestDoContextResultWinsOverCancel(t *testing.T) {
+	for range 100 {
+		var g Group[string, string]
+
+		started := make(chan struct{})
+		block := make(chan struct{})
+		leader := make(chan struct{})
+		go func() {
+			defer close(leader)
+			g.Do("key", func() (string, error) {
+				close(started)
+				<-block
+				return "bar", nil
+			})
+		}()
+		<-started
+
+		inner, cancel := context.WithCancel(t.Context())
+		ctx := &gatedContext{Context: inner, entered: make(chan struct{}), gate: make(chan struct{})}
+		type result struct {
+			v   string
+			err error
+		}
+		waiter := make(chan result, 1)
+		go func() {
+			v, _, err := g.DoContext(ctx, "key", func(context.Context) (string, error) {
+				panic("unreachable")
+			})
+			waiter <- result{v, err}
+		}()
+
+		// The waiter is about to select on both the result and its context:
+		// make both ready at once.
+		<-ctx.entered
+		close(block)
+		<-leader
+		cancel()
+		close(ctx.gate)
+
+		r := <-waiter
+		require.NoError(t, r.err)
+		require.Equal(t, "bar", r.v)
+	}
+}
+
+func TestDoKey(t *testing.T) {
+	var g Group[int, string]
+	v, shared, err := g.DoKey(42, func(key int) (string, error) {
+		return fmt.Sprint(key), nil
+	})
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "42", v)
+}
+
+// BenchmarkDo measures the cost of a [Group.Do] call leading a new call for
+// each key.
+func BenchmarkDo(b *testing.B) {
+	var g Group[int, int]
+	b.ReportAllocs()
+	for i := range b.N {
+		g.Do(i, func() (int, error) { return i, nil })
+	}
+}
+
+func BenchmarkDoKey(b *testing.B) {
+	fetch := func(key int) (int, error) { return key, nil }
+
+	b.Run("Closure", func(b *testing.B) {
+		var g Group[int, int]
+		b.ReportAllocs()
+		for i := range b.N {
+			g.Do(i, func() (int, error) { return fetch(i) })
+		}
+	})
+	b.Run("DoKey", func(b *testing.B) {
+		var g Group[int, int]
+		b.ReportAllocs()
+		for i := range b.N {
+			g.DoKey(i, fetch)
+		}
+	})
+}
+
+func TestFlush(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	g.Set("cached", "bar")
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	fnDone := make(chan struct{})
+	errs := make(chan error, 3)
+	go func() {
+		_, _, err := g.Do("key", func() (string, error) {
+			defer close(fnDone)
+			close(started)
+			<-block
+			return "stale", nil
+		})
+		errs <- err
+	}()
+	<-started
+	for range 2 {
+		go func() {
+			_, _, err := g.Do("key", func() (string, error) { panic("unreachable") })
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure the waiters joined.
+
+	g.Flush()
+	require.Zero(t, g.Len())
+	// The waiters are released right away, while the function still runs.
+	for range 2 {
+		require.ErrorIs(t, <-errs, ErrFlushed)
+	}
+
+	// New callers start a new call.
+	v, _, err := g.Do("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+
+	// The leader gets ErrFlushed too, its result is discarded.
+	close(block)
+	<-fnDone
+	require.ErrorIs(t, <-errs, ErrFlushed)
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithDisabled(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute, WithDisabled(true))
+
+	const n = 8
+	var calls atomic.Int32
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, shared, err := g.Do("key", func() (string, error) {
+				calls.Add(1)
+				<-block
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.False(t, shared)
+			require.Equal(t, "bar", v)
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure all goroutines are running.
+	require.Equal(t, int32(n), calls.Load())
+	require.Zero(t, g.Len())
+	close(block)
+	wg.Wait()
+
+	// Nothing is cached.
+	require.Zero(t, g.Len())
+	_, _, err := g.Do("key", func() (string, error) {
+		calls.Add(1)
+		return "bar", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(n+1), calls.Load())
+	require.Equal(t, Stats{Calls: n + 1, Executions: n + 1}, g.Stats())
+}
+
+func TestWithErrorFailover(t *testing.T) {
+	g := New[string, string](WithErrorFailover())
+	someErr := errors.New("some error")
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	leader := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do("key", func() (string, error) {
+			close(started)
+			<-block
+			return "", someErr
+		})
+		leader <- err
+	}()
+	<-started
+
+	const n = 4
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, shared, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) {
+				calls.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, "bar", v)
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure the waiters joined.
+
+	// The waiters fail over to a single new execution.
+	close(block)
+	require.ErrorIs(t, <-leader, someErr)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+	require.Equal(t, int64(2), g.Stats().Executions)
+}
+
+func TestWithErrorFailoverKeepsFailing(t *testing.T) {
+	g := New[string, string](WithErrorFailover())
+	someErr := errors.New("some error")
+
+	const n = 4
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, _, err := g.Do("key", func() (string, error) {
+				calls.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return "", someErr
+			})
+			require.ErrorIs(t, err, someErr)
+		})
+	}
+	wg.Wait()
+	require.LessOrEqual(t, calls.Load(), int32(n))
+}
+
+func TestWait(t *testing.T) {
+	g := New[string, string]()
+
+	// Wait returns immediately without in-flight calls.
+	g.Wait()
+
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, key := range []string{"foo", "bar"} {
+		wg.Go(func() {
+			_, _, err := g.Do(key, func() (string, error) {
+				<-block
+				return key, nil
+			})
+			require.NoError(t, err)
+		})
+	}
+	time.Sleep(10 * time.Millisecond) // Ensure the calls are in flight.
+
+	waited := make(chan struct{})
+	go func() {
+		g.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("Wait returned with calls in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(block)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return once the calls completed")
+	}
+	wg.Wait()
+}
+
+func TestWaitConcurrent(t *testing.T) {
+	// Waiters racing with executions starting and stopping must never miss
+	// the moment the group goes idle.
+	var r running
+	var wg sync.WaitGroup
+	for range 100 {
+		r.start()
+		wg.Go(r.stop)
+		wg.Go(r.wait)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait missed the group going idle")
+	}
+}
+
+func TestClone(t *testing.T) {
+	const ttl = 20 * time.Millisecond
+	g := NewWithTTL[string, string](ttl, WithMaxConcurrency(1))
+	mustDo(t, g, "key", "foo")
+
+	// The clone doesn't share the cache of g.
+	c := g.Clone()
+	_, ok := c.Peek("key")
+	require.False(t, ok)
+	require.Zero(t, c.Len())
+
+	// Nor its concurrency limit.
+	release := make(chan struct{})
+	go g.Do("busy", func() (string, error) {
+		<-release
+		return "busy", nil
+	})
+	time.Sleep(10 * time.Millisecond) // Let g hold its only slot.
+	mustDo(t, c, "key", "bar")
+	close(release)
+
+	// But it caches results for the same TTL.
+	v, shared, err := c.Do("key", func() (string, error) { return "baz", nil })
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, "bar", v)
+	time.Sleep(ttl)
+	mustDo(t, c, "key", "baz")
+}
+
+func TestWithValueCloner(t *testing.T) {
+	g := NewWithTTL[string, []int](time.Minute, WithValueCloner(slices.Clone[[]int]))
+
+	const n = 4
+	block := make(chan struct{})
+	followers := make(chan []int, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, info, err := g.DoDetailed("key", func() ([]int, error) {
+				<-block
+				return []int{3, 1, 2}, nil
+			})
+			require.NoError(t, err)
+			if !info.Leader {
+				followers <- v
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond) // Ensure the callers joined.
+	close(block)
+	wg.Wait()
+	close(followers)
+
+	// A follower mutating its value doesn't affect the others.
+	var values [][]int
+	for v := range followers {
+		values = append(values, v)
+	}
+	require.Len(t, values, n-1)
+	slices.Sort(values[0])
+	values[1][0] = 42
+	require.Equal(t, []int{3, 1, 2}, values[2])
+
+	// Nor the cached value.
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, []int{3, 1, 2}, v)
+	v[0] = 42
+	v, _, err := g.Do("key", func() ([]int, error) { panic("unreachable") })
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 1, 2}, v)
+}
+
+func TestWithJoinPolicy(t *testing.T) {
+	const maxAge = 30 * time.Millisecond
+	g := New[string, string](WithJoinPolicy(func(age time.Duration, _ context.Context) bool {
+		return age < maxAge
+	}))
+
+	block := make(chan struct{})
+	var executions atomic.Int32
+	do := func(v string) <-chan Result[string] {
+		return g.DoChan("key", func() (string, error) {
+			executions.Add(1)
+			<-block
+			return v, nil
+		})
+	}
+
+	// A young call is joined.
+	old := do("old")
+	time.Sleep(5 * time.Millisecond) // Ensure the call started.
+	joined := do("unreachable")
+	time.Sleep(5 * time.Millisecond) // Ensure the caller joined.
+	require.Equal(t, int32(1), executions.Load())
+
+	// An old call is not, a fresh one replaces it.
+	time.Sleep(maxAge)
+	fresh := do("fresh")
+	time.Sleep(5 * time.Millisecond) // Ensure the call started.
+	require.Equal(t, int32(2), executions.Load())
+	joinedFresh := do("unreachable")
+	time.Sleep(5 * time.Millisecond) // Ensure the caller joined.
+	require.Equal(t, int32(2), executions.Load())
+
+	close(block)
+	for ch, want := range map[<-chan Result[string]]Result[string]{
+		old:         {Val: "old", Shared: true, Waiters: 1},
+		joined:      {Val: "old", Shared: true, Waiters: 1},
+		fresh:       {Val: "fresh", Shared: true, Waiters: 1},
+		joinedFresh: {Val: "fresh", Shared: true, Waiters: 1},
+	} {
+		require.Equal(t, want, <-ch)
+	}
+	require.Zero(t, g.Len())
+}
+
+func TestDoContextReentrant(t *testing.T) {
+	var g Group[string, string]
+
+	// Direct recursion.
+	v, _, err := g.DoContext(t.Context(), "key", func(ctx context.Context) (string, error) {
+		_, _, err := g.DoContext(ctx, "key", func(context.Context) (string, error) { panic("unreachable") })
+		require.ErrorIs(t, err, ErrReentrant)
+		return "bar", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+
+	// Indirect recursion through another key.
+	_, _, err = g.DoContext(t.Context(), "a", func(ctx context.Context) (string, error) {
+		_, _, err := g.DoContext(ctx, "b", func(ctx context.Context) (string, error) {
+			_, _, err := g.DoContext(ctx, "a", func(context.Context) (string, error) { panic("unreachable") })
+			return "", err
+		})
+		return "", err
+	})
+	require.ErrorIs(t, err, ErrReentrant)
+	require.Zero(t, g.Len())
+}
+
+func TestDoNestedJoin(t *testing.T) {
+	var g Group[string, string]
+
+	// A function joining the call of another goroutine is not re-entrant.
+	started := make(chan struct{})
+	block := make(chan struct{})
+	go g.Do("a", func() (string, error) {
+		close(started)
+		<-block
+		return "bar", nil
+	})
+	<-started
+	go func() {
+		time.Sleep(20 * time.Millisecond) // Ensure the call is joined.
+		close(block)
+	}()
+	v, _, err := g.Do("b", func() (string, error) {
+		v, _, err := g.Do("a", func() (string, error) { panic("unreachable") })
+		return v, err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithAggregateErrors(t *testing.T) {
+	g := New[string, string](WithAggregateErrors())
+	err1, err2 := errors.New("first attempt"), errors.New("second attempt")
+
+	leaderCtx, cancelLeader := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		_, _, err := g.DoContext(leaderCtx, "key", func(ctx context.Context) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", err1
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	}()
+	<-started
+
+	waiterDone := make(chan struct{})
+	go func() {
+		defer close(waiterDone)
+		_, _, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) {
+			return "", err2
+		})
+		require.ErrorIs(t, err, err1)
+		require.ErrorIs(t, err, err2)
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the waiter joined.
+
+	// The promoted waiter fails too, and gets both errors.
+	cancelLeader()
+	<-leaderDone
+	<-waiterDone
+
+	// A single attempt returns its own error.
+	_, _, err := g.Do("key", func() (string, error) { return "", err2 })
+	require.Equal(t, err2, err)
+}
+
+func TestDoLocked(t *testing.T) {
+	const n = 16
+	g := New[string, string](WithSynchronizer(NewSynchronizer(n - 1)))
+
+	var afters atomic.Int32
+	var index sync.Map
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, shared, err := g.DoLocked("key", func() (string, error) { return "bar", nil }, func(v string) {
+				afters.Add(1)
+				index.Store("key", v)
+			})
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, "bar", v)
+
+			// after ran before any caller got the result.
+			indexed, ok := index.Load("key")
+			require.True(t, ok)
+			require.Equal(t, "bar", indexed)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), afters.Load())
+
+	// after is not called on errors.
+	_, _, err := new(Group[string, string]).DoLocked("key", func() (string, error) { return "", errors.New("some error") }, func(string) {
+		panic("unreachable")
+	})
+	require.Error(t, err)
+}
+
+func TestWithPanicHandler(t *testing.T) {
+	type incident struct {
+		key       string
+		recovered any
+	}
+	var mu sync.Mutex
+	var incidents []incident
+	handler := WithPanicHandler(func(key string, recovered any, stack []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		incidents = append(incidents, incident{key, recovered})
+		require.Contains(t, string(stack), "TestWithPanicHandler")
+		panic("handler panics too") // Recovered by the group.
+	})
+
+	const n = 8
+	g := New[string, string](handler, WithPanicToError(), WithSynchronizer(NewSynchronizer(n-1)))
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, _, err := g.Do("key", func() (string, error) { panic("boom") })
+			var panicErr *PanicError
+			require.ErrorAs(t, err, &panicErr)
+			require.Equal(t, "boom", panicErr.Value)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, []incident{{"key", "boom"}}, incidents)
+
+	// Without WithPanicToError, the handler fires before the panic is
+	// propagated.
+	g = New[string, string](handler)
+	require.PanicsWithValue(t, "boom", func() {
+		g.Do("other", func() (string, error) { panic("boom") })
+	})
+	require.Equal(t, []incident{{"key", "boom"}, {"other", "boom"}}, incidents)
+}
+
+func TestWithFallback(t *testing.T) {
+	errNotFound := errors.New("not found")
+	fallback := WithFallback(func(key string, err error) (string, bool) {
+		if errors.Is(err, errNotFound) {
+			return "default " + key, true
+		}
+		return "", false
+	})
+
+	const n = 8
+	g := New[string, string](WithTTL(time.Minute), WithErrorTTL(time.Minute), fallback, WithSynchronizer(NewSynchronizer(n-1)))
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, _, err := g.Do("key", func() (string, error) {
+				calls.Add(1)
+				return "", errNotFound
+			})
+			require.NoError(t, err)
+			require.Equal(t, "default key", v)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+	require.Equal(t, int64(1), g.Stats().Errors)
+
+	// The fallback value is not cached, nor is the error.
+	_, ok := g.Peek("key")
+	require.False(t, ok)
+	require.Zero(t, g.Len())
+
+	// Errors rejected by the fallback are returned as usual.
+	errOther := errors.New("other")
+	_, _, err := New[string, string](fallback).Do("key", func() (string, error) { return "", errOther })
+	require.ErrorIs(t, err, errOther)
+}
+
+func TestDoCount(t *testing.T) {
+	v, shared, err := new(Group[string, int32]).DoCount("key", func(waiters int32) (int32, error) { return waiters, nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Zero(t, v)
+
+	const n = 8
+	g := New[string, int32](WithSynchronizer(NewSynchronizer(n - 1)))
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, _, err := g.DoCount("key", func(waiters int32) (int32, error) {
+				calls.Add(1)
+				return waiters, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, int32(n-1), v)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func BenchmarkDoHotKey(b *testing.B) {
+	g := NewWithTTL[string, int](time.Hour)
+	fn := func() (int, error) { return 42, nil }
+	g.Do("hot", fn)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.Do("hot", fn)
+		}
+	})
+}
+
+// BenchmarkDoJoin measures the cost of joining a call in flight. The callers
+// join with a context already done so that they leave right away instead of
+// waiting for the blocked leader.
+func BenchmarkDoJoin(b *testing.B) {
+	g := New[string, int]()
+	release := make(chan struct{})
+	led := make(chan struct{})
+	go g.Do("key", func() (int, error) {
+		close(led)
+		<-release
+		return 42, nil
+	})
+	<-led
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fn := func(context.Context) (int, error) { return 0, nil }
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := g.DoContext(ctx, "key", fn); !errors.Is(err, context.Canceled) {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestWithValidator(t *testing.T) {
+	errPartial := errors.New("partial value")
+	validator := WithValidator(func(v string) error {
+		if strings.HasSuffix(v, "...") {
+			return errPartial
+		}
+		return nil
+	})
+
+	const n = 8
+	g := New[string, string](WithTTL(time.Minute), validator)
+	block := make(chan struct{})
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, _, err := g.Do("key", func() (string, error) {
+				calls.Add(1)
+				<-block
+				return "incompl...", nil
+			})
+			require.ErrorIs(t, err, errPartial)
+			require.Empty(t, v)
+		})
+	}
+	require.Eventually(t, func() bool { return g.Waiters("key") == n }, time.Second, time.Millisecond)
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+	require.Zero(t, g.Len())
+
+	// The next call executes its function again.
+	v, shared, err := g.Do("key", func() (string, error) { return "complete", nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, "complete", v)
+	cached, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "complete", cached)
+}
+
+func TestWithValidatorDoLocked(t *testing.T) {
+	errPartial := errors.New("partial value")
+	var validations atomic.Int32
+	g := New[string, string](WithValidator(func(v string) error {
+		validations.Add(1)
+		if strings.HasSuffix(v, "...") {
+			return errPartial
+		}
+		return nil
+	}))
+
+	// after is not called with a rejected value.
+	var indexed []string
+	after := func(v string) { indexed = append(indexed, v) }
+	_, _, err := g.DoLocked("key", func() (string, error) { return "incompl...", nil }, after)
+	require.ErrorIs(t, err, errPartial)
+	require.Empty(t, indexed)
+
+	v, _, err := g.DoLocked("key", func() (string, error) { return "complete", nil }, after)
+	require.NoError(t, err)
+	require.Equal(t, "complete", v)
+	require.Equal(t, []string{"complete"}, indexed)
+	require.Equal(t, int32(2), validations.Load()) // Once per execution.
+}
+
+func TestCancel(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	g.Cancel("key") // No call in flight.
+
+	const n = 4
+	var calls atomic.Int32
+	fn := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, _, err := g.DoContext(t.Context(), "key", fn)
+			require.ErrorIs(t, err, context.Canceled)
+		})
+	}
+	require.Eventually(t, func() bool { return g.Waiters("key") == n }, time.Second, time.Millisecond)
+	g.Cancel("key")
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+
+	// The cancellation error is not cached, and cached results are not
+	// affected.
+	v, _, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+	g.Cancel("key")
+	v, ok := g.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithBackgroundLeader(t *testing.T) {
+	var submitted atomic.Int32
+	g := New[string, string](WithBackgroundLeader(func(task func()) {
+		submitted.Add(1)
+		go task()
+	}))
+
+	type callerKey struct{}
+	started := make(chan struct{})
+	block := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		// The function runs with the values of the first caller, but is
+		// not canceled with it.
+		require.Equal(t, "first", ctx.Value(callerKey{}))
+		close(started)
+		select {
+		case <-block:
+			return "bar", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// The first caller is not stuck executing fn: it stops waiting once its
+	// context is done.
+	ctx, cancel := context.WithCancel(context.WithValue(t.Context(), callerKey{}, "first"))
+	first := make(chan error)
+	go func() {
+		_, shared, err := g.DoContext(ctx, "key", fn)
+		require.True(t, shared)
+		first <- err
+	}()
+	<-started
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+
+	const n = 4
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, shared, err := g.DoContext(t.Context(), "key", fn)
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, "bar", v)
+		})
+	}
+	require.Eventually(t, func() bool { return g.Waiters("key") == n+1 }, time.Second, time.Millisecond)
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), submitted.Load())
+	require.Equal(t, Stats{Calls: n + 1, Executions: 1, Shared: n + 1}, g.Stats())
+
+	// A panic is propagated to the callers.
+	require.PanicsWithValue(t, "boom", func() {
+		g.Do("panic", func() (string, error) { panic("boom") })
+	})
+}
+
+func TestDoPriority(t *testing.T) {
+	var g Group[string, int]
+
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(t.Context())
+	leader := make(chan error)
+	go func() {
+		_, _, err := g.DoContext(ctx, "key", func(ctx context.Context) (int, error) {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		leader <- err
+	}()
+	<-started
+
+	priorities := []int{1, -2, 5, 0, 3}
+	var executed []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, priority := range priorities {
+		wg.Go(func() {
+			v, shared, err := g.DoPriority(t.Context(), "key", priority, func(context.Context) (int, error) {
+				mu.Lock()
+				executed = append(executed, priority)
+				mu.Unlock()
+				return priority, nil
+			})
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, 5, v)
+		})
+	}
+	require.Eventually(t, func() bool { return g.Waiters("key") == int32(len(priorities)+1) }, time.Second, time.Millisecond)
+
+	// The highest priority waiter takes over once the leader steps down.
+	cancel()
+	require.ErrorIs(t, <-leader, context.Canceled)
+	wg.Wait()
+	require.Equal(t, []int{5}, executed)
+}
+
+func TestDoPriorityAbandoned(t *testing.T) {
+	var g Group[string, int]
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	ctx, cancel := context.WithCancel(t.Context())
+	go g.DoContext(ctx, "key", func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		<-block // Leave time to the top waiter to give up.
+		return 0, ctx.Err()
+	})
+	<-started
+
+	// The highest priority waiter gives up before the leader steps down,
+	// the next one takes over.
+	topCtx, cancelTop := context.WithCancel(t.Context())
+	top := make(chan error)
+	go func() {
+		_, _, err := g.DoPriority(topCtx, "key", 10, func(context.Context) (int, error) { return 10, nil })
+		top <- err
+	}()
+	next := make(chan int)
+	go func() {
+		v, _, err := g.DoPriority(t.Context(), "key", 1, func(context.Context) (int, error) { return 1, nil })
+		require.NoError(t, err)
+		next <- v
+	}()
+	require.Eventually(t, func() bool { return g.Waiters("key") == 3 }, time.Second, time.Millisecond)
+	cancelTop()
+	require.ErrorIs(t, <-top, context.Canceled)
+	cancel()
+	close(block)
+	require.Equal(t, 1, <-next)
+}
+
+func TestForgetMany(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := New[string, string](WithTTL(time.Minute), opt)
+	for _, key := range []string{"a", "b", "c"} {
+		mustDo(t, g, key, key)
+	}
+	require.Zero(t, g.ForgetMany(nil))
+
+	require.Equal(t, 2, g.ForgetMany([]string{"a", "absent", "c", "a"}))
+	require.Equal(t, []string{"b"}, slices.Collect(g.Keys()))
+	require.Equal(t, int64(4), g.Stats().Forgets)
+	require.Equal(t, []eviction{{"a", "a", Forgotten}, {"c", "c", Forgotten}}, evictions())
+}
@@ -0,0 +1,38 @@
+// Package inflightcompat provides a drop-in replacement for the Group of
+// [golang.org/x/sync/singleflight], backed by an [inflight.Group], to ease the
+// mechanical migration of existing code before adopting generics.
+//
+// All types in this package are safe for concurrent use by multiple goroutines.
+package inflightcompat
+
+import "github.com/wazazaby/inflight"
+
+// Result holds the results of [StringGroup.DoChan], like the Result of
+// x/sync/singleflight.
+type Result = inflight.Result[any]
+
+// StringGroup has the API of the Group of x/sync/singleflight, backed by an
+// [inflight.Group] keyed by strings.
+//
+// The zero value of StringGroup is ready to use.
+type StringGroup struct {
+	g inflight.Group[string, any]
+}
+
+// Do is like [inflight.Group.Do], but returns its results in the order of
+// x/sync/singleflight: the value, the error and then whether it was shared,
+// while [inflight.Group.Do] returns the shared bool before the error.
+func (g *StringGroup) Do(key string, fn func() (any, error)) (v any, err error, shared bool) {
+	v, shared, err = g.g.Do(key, fn)
+	return v, err, shared
+}
+
+// DoChan is like [inflight.Group.DoChan].
+func (g *StringGroup) DoChan(key string, fn func() (any, error)) <-chan Result {
+	return g.g.DoChan(key, fn)
+}
+
+// Forget is like [inflight.Group.Forget].
+func (g *StringGroup) Forget(key string) {
+	g.g.Forget(key)
+}
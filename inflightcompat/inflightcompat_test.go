@@ -0,0 +1,85 @@
+package inflightcompat
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	var g StringGroup
+	v, err, _ := g.Do("key", func() (any, error) {
+		return "bar", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+}
+
+func TestDoErr(t *testing.T) {
+	var g StringGroup
+	someErr := errors.New("some error")
+	v, err, _ := g.Do("key", func() (any, error) {
+		return nil, someErr
+	})
+	require.ErrorIs(t, err, someErr)
+	require.Nil(t, v)
+}
+
+func TestDoDupSuppress(t *testing.T) {
+	var g StringGroup
+	var calls atomic.Int32
+	block := make(chan struct{})
+
+	const n = 10
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, err, shared := g.Do("key", func() (any, error) {
+				calls.Add(1)
+				<-block
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, "bar", v)
+		})
+	}
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestForget(t *testing.T) {
+	var g StringGroup
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	first := g.DoChan("key", func() (any, error) {
+		close(started)
+		<-block
+		return 1, nil
+	})
+	<-started
+
+	g.Forget("key")
+	second := g.DoChan("key", func() (any, error) { return 2, nil })
+	require.Equal(t, 2, (<-second).Val)
+
+	close(block)
+	require.Equal(t, 1, (<-first).Val)
+}
+
+func TestDoChan(t *testing.T) {
+	var g StringGroup
+	res := <-g.DoChan("key", func() (any, error) {
+		return "bar", nil
+	})
+	require.NoError(t, res.Err)
+	require.False(t, res.Shared)
+	require.Equal(t, "bar", res.Val)
+}
@@ -0,0 +1,130 @@
+// Package inflightotel traces the executions of an [inflight.Group] with
+// OpenTelemetry, through the observer hook of the group (see
+// [inflight.WithObserver]), so that the inflight package itself does not
+// depend on OpenTelemetry.
+//
+// All types in this package are safe for concurrent use by multiple goroutines.
+package inflightotel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/wazazaby/inflight"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanName is the name of the spans created around the executions.
+const SpanName = "inflight.execute"
+
+// Attribute keys of the spans created around the executions.
+const (
+	KeyAttribute     = attribute.Key("inflight.key")     // key of the call, formatted by inflight.Group.KeyString.
+	SharedAttribute  = attribute.Key("inflight.shared")  // whether the result was shared with other callers.
+	WaitersAttribute = attribute.Key("inflight.waiters") // number of callers which joined the execution.
+)
+
+// WithTracer traces every execution of a function of the group with tracer:
+// its leader creates a span named [SpanName], child of the span of its
+// context, and ended once the function returns. Every caller joining the
+// execution adds a link to this span to the span of its own context, so that
+// the traces of deduplicated calls lead to the execution serving them.
+//
+// The span of an execution whose function panics is ended with an
+// [*inflight.PanicError], before the panic is propagated.
+//
+// The type parameters must match the ones of the group given the option.
+func WithTracer[K comparable, V any](tracer trace.Tracer) inflight.Option {
+	return inflight.WithObserver[K, V](&observer[K, V]{
+		tracer:     tracer,
+		executions: make(map[K][]*execution),
+	})
+}
+
+// observer is the [inflight.Observer] created by [WithTracer].
+type observer[K comparable, V any] struct {
+	tracer trace.Tracer
+
+	mu         sync.Mutex
+	executions map[K][]*execution // executions in flight, oldest first.
+}
+
+// execution is an execution of a function in flight.
+type execution struct {
+	span    trace.Span
+	waiters int
+}
+
+var (
+	_ inflight.ExecutionObserver[string]   = (*observer[string, any])(nil)
+	_ inflight.JoinObserver[string]        = (*observer[string, any])(nil)
+	_ inflight.KeyStringerObserver[string] = (*observer[string, any])(nil)
+)
+
+// OnStart does nothing, the span is started by OnExecute.
+func (o *observer[K, V]) OnStart(K) {}
+
+// OnExecute is like OnExecuteString, with key formatted by [fmt.Sprint].
+func (o *observer[K, V]) OnExecute(ctx context.Context, key K) func(bool, error, time.Duration) {
+	return o.OnExecuteString(ctx, key, fmt.Sprint(key))
+}
+
+// OnExecuteString starts the span of an execution of the function of key,
+// formatted as keyString, and returns the function ending it.
+func (o *observer[K, V]) OnExecuteString(ctx context.Context, key K, keyString string) func(bool, error, time.Duration) {
+	_, span := o.tracer.Start(ctx, SpanName,
+		trace.WithAttributes(KeyAttribute.String(keyString)))
+	e := &execution{span: span}
+	o.mu.Lock()
+	o.executions[key] = append(o.executions[key], e)
+	o.mu.Unlock()
+	return func(shared bool, err error, _ time.Duration) {
+		o.end(key, e, shared, err)
+	}
+}
+
+// OnJoin does nothing, the span is linked by OnJoinContext.
+func (o *observer[K, V]) OnJoin(K) {}
+
+// OnJoinContext links the span of ctx to the span of the latest execution in
+// flight for key, if any. Callers served by a cached result are not linked.
+func (o *observer[K, V]) OnJoinContext(ctx context.Context, key K) {
+	o.mu.Lock()
+	executions := o.executions[key]
+	if len(executions) == 0 {
+		o.mu.Unlock()
+		return
+	}
+	e := executions[len(executions)-1]
+	e.waiters++
+	o.mu.Unlock()
+	trace.SpanFromContext(ctx).AddLink(trace.Link{SpanContext: e.span.SpanContext()})
+}
+
+// OnFinish does nothing, the span is ended by the function returned by
+// OnExecute.
+func (o *observer[K, V]) OnFinish(K, bool, error, time.Duration) {}
+
+// end removes e from the executions in flight for key, and ends its span.
+func (o *observer[K, V]) end(key K, e *execution, shared bool, err error) {
+	o.mu.Lock()
+	executions := slices.DeleteFunc(o.executions[key], func(x *execution) bool { return x == e })
+	if len(executions) == 0 {
+		delete(o.executions, key)
+	} else {
+		o.executions[key] = executions
+	}
+	waiters := e.waiters
+	o.mu.Unlock()
+	e.span.SetAttributes(SharedAttribute.Bool(shared), WaitersAttribute.Int(waiters))
+	if err != nil {
+		e.span.RecordError(err)
+		e.span.SetStatus(codes.Error, err.Error())
+	}
+	e.span.End()
+}
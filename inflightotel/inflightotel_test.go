@@ -0,0 +1,177 @@
+package inflightotel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wazazaby/inflight"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracerProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func TestWithTracer(t *testing.T) {
+	tp, recorder := newTracerProvider()
+	tracer := tp.Tracer("test")
+	g := inflight.New[string, string](WithTracer[string, string](tracer))
+
+	const n = 4
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			ctx, span := tracer.Start(t.Context(), "caller")
+			defer span.End()
+			v, _, err := g.DoContext(ctx, "key", func(context.Context) (string, error) {
+				close(started)
+				<-block
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "bar", v)
+		})
+		if i == 0 {
+			<-started // Ensure the first caller leads the call.
+		}
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure the waiters joined.
+	close(block)
+	wg.Wait()
+
+	var executions, callers []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case SpanName:
+			executions = append(executions, span)
+		case "caller":
+			callers = append(callers, span)
+		}
+	}
+
+	// One span for the execution, with the expected attributes, child of the
+	// span of the leader.
+	require.Len(t, executions, 1)
+	execution := executions[0]
+	require.ElementsMatch(t, []attribute.KeyValue{
+		KeyAttribute.String("key"),
+		SharedAttribute.Bool(true),
+		WaitersAttribute.Int(n - 1),
+	}, execution.Attributes())
+	require.Equal(t, codes.Unset, execution.Status().Code)
+
+	// The other callers link to it.
+	require.Len(t, callers, n)
+	var linked int
+	for _, caller := range callers {
+		if caller.SpanContext().SpanID() == execution.Parent().SpanID() {
+			require.Empty(t, caller.Links())
+			continue
+		}
+		require.Len(t, caller.Links(), 1)
+		require.Equal(t, execution.SpanContext(), caller.Links()[0].SpanContext)
+		linked++
+	}
+	require.Equal(t, n-1, linked)
+}
+
+func TestWithTracerError(t *testing.T) {
+	tp, recorder := newTracerProvider()
+	g := inflight.New[int, string](WithTracer[int, string](tp.Tracer("test")))
+
+	someErr := errors.New("some error")
+	_, _, err := g.Do(42, func() (string, error) { return "", someErr })
+	require.ErrorIs(t, err, someErr)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.ElementsMatch(t, []attribute.KeyValue{
+		KeyAttribute.String("42"),
+		SharedAttribute.Bool(false),
+		WaitersAttribute.Int(0),
+	}, spans[0].Attributes())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, someErr.Error(), spans[0].Status().Description)
+	require.Len(t, spans[0].Events(), 1) // The recorded error.
+}
+
+func TestWithTracerKeyStringer(t *testing.T) {
+	type userKey struct {
+		tenant string
+		id     int
+	}
+	tp, recorder := newTracerProvider()
+	g := inflight.New[userKey, string](
+		WithTracer[userKey, string](tp.Tracer("test")),
+		inflight.WithKeyStringer(func(k userKey) string { return fmt.Sprintf("%s/%d", k.tenant, k.id) }),
+	)
+	_, _, err := g.Do(userKey{"acme", 42}, func() (string, error) { return "", nil })
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Contains(t, spans[0].Attributes(), KeyAttribute.String("acme/42"))
+}
+
+func TestWithTracerPanic(t *testing.T) {
+	tp, recorder := newTracerProvider()
+	g := inflight.New[string, string](WithTracer[string, string](tp.Tracer("test")))
+
+	require.Panics(t, func() {
+		_, _, _ = g.Do("key", func() (string, error) { panic("boom") })
+	})
+	_, _, err := g.Do("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+
+	// Both spans are ended, the one of the panic with an error.
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestWithTracerConcurrentExecutions(t *testing.T) {
+	tp, recorder := newTracerProvider()
+	g := inflight.New[string, string](WithTracer[string, string](tp.Tracer("test")))
+
+	someErr := errors.New("some error")
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, err := g.DoFresh("key", func() (string, error) {
+			close(started)
+			<-block
+			return "", someErr
+		})
+		require.ErrorIs(t, err, someErr)
+	}()
+	<-started
+
+	// The second execution of the key ends first, with its own span.
+	_, _, err := g.DoFresh("key", func() (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	close(block)
+	<-done
+	spans = recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, someErr.Error(), spans[1].Status().Description)
+	require.True(t, spans[1].StartTime().Before(spans[0].StartTime()))
+}
@@ -0,0 +1,65 @@
+// Package inflightprom exposes the statistics of an [inflight.Group] as
+// Prometheus metrics, so that the inflight package itself does not depend on
+// Prometheus.
+//
+// All types in this package are safe for concurrent use by multiple goroutines.
+package inflightprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wazazaby/inflight"
+)
+
+var (
+	keysDesc = prometheus.NewDesc("inflight_keys",
+		"Number of keys with a call in flight or a cached result.", nil, nil)
+	callsDesc = prometheus.NewDesc("inflight_calls_total",
+		"Number of calls made to the group.", nil, nil)
+	executionsDesc = prometheus.NewDesc("inflight_executions_total",
+		"Number of executions of a function.", nil, nil)
+	sharedDesc = prometheus.NewDesc("inflight_shared_total",
+		"Number of calls whose result was shared with other callers.", nil, nil)
+	errorsDesc = prometheus.NewDesc("inflight_errors_total",
+		"Number of executions of a function which returned an error or panicked.", nil, nil)
+	forgetsDesc = prometheus.NewDesc("inflight_forgets_total",
+		"Number of calls to Forget.", nil, nil)
+)
+
+// Collector is a [prometheus.Collector] exposing the statistics of a group,
+// see [inflight.Group.Stats] and [inflight.Group.Len]. Its metrics are read
+// from the group on every scrape.
+//
+// To collect the metrics of several groups in the same registry, register
+// their collectors with distinct labels, for instance with
+// [prometheus.WrapRegistererWith].
+type Collector[K comparable, V any] struct {
+	g *inflight.Group[K, V]
+}
+
+// NewCollector creates a new [Collector] exposing the statistics of g.
+func NewCollector[K comparable, V any](g *inflight.Group[K, V]) *Collector[K, V] {
+	return &Collector[K, V]{g: g}
+}
+
+var _ prometheus.Collector = (*Collector[string, any])(nil)
+
+// Describe implements [prometheus.Collector].
+func (c *Collector[K, V]) Describe(ch chan<- *prometheus.Desc) {
+	ch <- keysDesc
+	ch <- callsDesc
+	ch <- executionsDesc
+	ch <- sharedDesc
+	ch <- errorsDesc
+	ch <- forgetsDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *Collector[K, V]) Collect(ch chan<- prometheus.Metric) {
+	stats := c.g.Stats()
+	ch <- prometheus.MustNewConstMetric(keysDesc, prometheus.GaugeValue, float64(c.g.Len()))
+	ch <- prometheus.MustNewConstMetric(callsDesc, prometheus.CounterValue, float64(stats.Calls))
+	ch <- prometheus.MustNewConstMetric(executionsDesc, prometheus.CounterValue, float64(stats.Executions))
+	ch <- prometheus.MustNewConstMetric(sharedDesc, prometheus.CounterValue, float64(stats.Shared))
+	ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(stats.Errors))
+	ch <- prometheus.MustNewConstMetric(forgetsDesc, prometheus.CounterValue, float64(stats.Forgets))
+}
@@ -0,0 +1,60 @@
+package inflightprom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/wazazaby/inflight"
+)
+
+func TestCollector(t *testing.T) {
+	g := inflight.NewWithTTL[string, string](time.Minute)
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(NewCollector(g)))
+
+	g.Do("foo", func() (string, error) { return "bar", nil })
+	g.Do("foo", func() (string, error) { return "baz", nil })
+	g.Do("failing", func() (string, error) { return "", errors.New("some error") })
+	g.Do("other", func() (string, error) { return "bar", nil })
+	g.Forget("other")
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP inflight_calls_total Number of calls made to the group.
+# TYPE inflight_calls_total counter
+inflight_calls_total 4
+# HELP inflight_errors_total Number of executions of a function which returned an error or panicked.
+# TYPE inflight_errors_total counter
+inflight_errors_total 1
+# HELP inflight_executions_total Number of executions of a function.
+# TYPE inflight_executions_total counter
+inflight_executions_total 3
+# HELP inflight_forgets_total Number of calls to Forget.
+# TYPE inflight_forgets_total counter
+inflight_forgets_total 1
+# HELP inflight_keys Number of keys with a call in flight or a cached result.
+# TYPE inflight_keys gauge
+inflight_keys 1
+# HELP inflight_shared_total Number of calls whose result was shared with other callers.
+# TYPE inflight_shared_total counter
+inflight_shared_total 1
+`)))
+}
+
+func TestCollectorSeveralGroups(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	for _, name := range []string{"users", "orders"} {
+		g := inflight.New[string, string]()
+		g.Do("foo", func() (string, error) { return "bar", nil })
+		wrapped := prometheus.WrapRegistererWith(prometheus.Labels{"group": name}, reg)
+		require.NoError(t, wrapped.Register(NewCollector(g)))
+	}
+
+	count, err := testutil.GatherAndCount(reg, "inflight_executions_total")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
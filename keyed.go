@@ -0,0 +1,44 @@
+package inflight
+
+import "context"
+
+// KeyedGroup is a [Group] whose keys are not comparable, such as slices or
+// structs holding slices. Every key is mapped to a string by a key function,
+// and the calls are deduplicated by that string.
+//
+// Keys mapped to the same string are considered equal: their calls are
+// deduplicated and share their results, even if the keys differ. The key
+// function must thus map distinct logical keys to distinct strings, for
+// instance with an unambiguous encoding of all their fields.
+//
+// KeyedGroup is safe for concurrent use by multiple goroutines.
+type KeyedGroup[K, V any] struct {
+	g   *Group[string, V]
+	key func(K) string
+}
+
+// NewKeyed creates a new [KeyedGroup] mapping its keys with kf, configured
+// with the given options.
+func NewKeyed[K, V any](kf func(K) string, opts ...Option) *KeyedGroup[K, V] {
+	return &KeyedGroup[K, V]{g: New[string, V](opts...), key: kf}
+}
+
+// Do is like [Group.Do], for the string mapped from key.
+func (k *KeyedGroup[K, V]) Do(key K, fn func() (V, error)) (V, bool, error) {
+	return k.g.Do(k.key(key), fn)
+}
+
+// DoContext is like [Group.DoContext], for the string mapped from key.
+func (k *KeyedGroup[K, V]) DoContext(ctx context.Context, key K, fn func(context.Context) (V, error)) (V, bool, error) {
+	return k.g.DoContext(ctx, k.key(key), fn)
+}
+
+// Forget is like [Group.Forget], for the string mapped from key.
+func (k *KeyedGroup[K, V]) Forget(key K) {
+	k.g.Forget(k.key(key))
+}
+
+// Close is like [Group.Close].
+func (k *KeyedGroup[K, V]) Close() error {
+	return k.g.Close()
+}
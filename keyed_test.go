@@ -0,0 +1,75 @@
+package inflight
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// query is a key which is not comparable.
+type query struct {
+	table string
+	ids   []string
+}
+
+func (q query) String() string {
+	return q.table + ":" + strings.Join(q.ids, ",")
+}
+
+func TestKeyedGroup(t *testing.T) {
+	g := NewKeyed[query, string](query.String)
+
+	var calls [2]atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			// Distinct slices holding the same values are the same key.
+			q := query{table: "users", ids: []string{"1", "2"}}
+			if i%2 == 1 {
+				q.ids = append(q.ids, "3")
+			}
+			v, _, err := g.Do(q, func() (string, error) {
+				calls[i%2].Add(1)
+				time.Sleep(20 * time.Millisecond)
+				return q.String(), nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, q.String(), v)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), calls[0].Load())
+	require.Equal(t, int32(1), calls[1].Load())
+}
+
+func TestKeyedGroupForget(t *testing.T) {
+	g := NewKeyed[query, string](query.String, WithTTL(time.Minute))
+	q := query{table: "users", ids: []string{"1"}}
+
+	var calls atomic.Int32
+	fn := func() (string, error) {
+		calls.Add(1)
+		return "bar", nil
+	}
+	for range 2 {
+		_, _, err := g.Do(q, fn)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), calls.Load())
+
+	g.Forget(query{table: "users", ids: []string{"1"}})
+	_, _, err := g.Do(q, fn)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestKeyedGroupClose(t *testing.T) {
+	g := NewKeyed[query, string](query.String, WithTTL(time.Minute), WithJanitor(time.Millisecond))
+	require.NoError(t, g.Close())
+	_, _, err := g.Do(query{table: "users"}, func() (string, error) { return "bar", nil })
+	require.ErrorIs(t, err, ErrClosed)
+}
@@ -0,0 +1,46 @@
+package inflight
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithKeyStringer makes the group format its keys with stringer whenever they
+// are meant to be read by humans, see [Group.KeyString]. This decouples the
+// type of the keys from their readable form, for instance for struct keys
+// whose default formatting is noisy or leaks fields.
+//
+// The type parameter of stringer must match the key type of the group given
+// the option.
+func WithKeyStringer[K comparable](stringer func(K) string) Option {
+	return func(o *options) { o.keyStringer = stringer }
+}
+
+// KeyStringerObserver is implemented by an [Observer] which formats the keys
+// it observes, for instance as span attributes or metric labels, the way the
+// group observed does.
+type KeyStringerObserver[K comparable] interface {
+	// OnExecuteString is like [ExecutionObserver.OnExecute], which it
+	// replaces, but also receives key formatted by [Group.KeyString]. An
+	// observer registered on several groups thus gets the keys formatted by
+	// each of them.
+	OnExecuteString(ctx context.Context, key K, keyString string) (done func(shared bool, err error, d time.Duration))
+}
+
+// KeyString returns the readable form of key, as formatted by the function
+// given to [WithKeyStringer], or by [fmt.Sprint] without it.
+//
+// KeyString is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) KeyString(key K) string {
+	return g.hooks.format(key)
+}
+
+// format formats key with the function given to [WithKeyStringer], or with
+// [fmt.Sprint] without it.
+func (h *hooks[K, V]) format(key K) string {
+	if h.keyString == nil {
+		return fmt.Sprint(key)
+	}
+	return h.keyString(key)
+}
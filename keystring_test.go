@@ -0,0 +1,56 @@
+package inflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type point struct{ x, y int }
+
+type stringerObserver struct {
+	recordingObserver[point, string]
+	mu   sync.Mutex
+	keys []string
+}
+
+func (o *stringerObserver) OnExecuteString(_ context.Context, _ point, keyString string) func(bool, error, time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys = append(o.keys, keyString)
+	return nil
+}
+
+func TestWithKeyStringer(t *testing.T) {
+	var zero Group[point, string]
+	require.Equal(t, "{1 2}", zero.KeyString(point{1, 2}))
+
+	o := new(stringerObserver)
+	stringer := WithKeyStringer(func(p point) string { return fmt.Sprintf("(%d, %d)", p.x, p.y) })
+	g := New[point, string](WithObserver[point, string](o), stringer)
+	require.Equal(t, "(1, 2)", g.KeyString(point{1, 2}))
+
+	// The observer gets the keys formatted by each of its groups.
+	other := New[point, string](WithObserver[point, string](o))
+	g.Do(point{1, 2}, func() (string, error) { return "", nil })
+	other.Do(point{1, 2}, func() (string, error) { return "", nil })
+	require.Equal(t, []string{"(1, 2)", "{1 2}"}, o.keys)
+}
+
+func TestWithKeyStringerClone(t *testing.T) {
+	o := new(stringerObserver)
+	g := New[point, string](WithObserver[point, string](o), WithKeyStringer(func(p point) string { return fmt.Sprint(p.x) }))
+
+	// Cloning the group does not touch the observer used by the calls.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() { g.Clone() })
+		wg.Go(func() { g.Do(point{i, 0}, func() (string, error) { return "", nil }) })
+	}
+	wg.Wait()
+	require.Len(t, o.keys, 8)
+}
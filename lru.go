@@ -0,0 +1,147 @@
+package inflight
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// WithMaxEntries caps the number of cached results of the group to n, along
+// with [WithTTL] or [WithErrorTTL]: once a result is cached beyond n, the
+// least recently used one is evicted. A cached result is used when it is
+// returned to a caller, or by [Group.Peek]. Calls in flight are never evicted,
+// nor counted.
+//
+// A value of n <= 0 means no limit, which is the default.
+func WithMaxEntries(n int) Option {
+	return func(o *options) { o.maxEntries = n }
+}
+
+// lru tracks the cached results of a group by recency, to evict the least
+// recently used ones beyond its capacity, see [WithMaxEntries], and their
+// total size, see [WithSizer]. A nil *lru tracks nothing.
+type lru[K comparable, V any] struct {
+	max   int          // no limit if <= 0.
+	sizer func(V) int  // nil if sizes are not tracked.
+	size  atomic.Int64 // total size of the entries.
+
+	mu   sync.Mutex
+	list list.List // of *lruEntry, the most recently used first.
+}
+
+// lruEntry is an element of an [lru].
+type lruEntry[K comparable, V any] struct {
+	key  K
+	call *call[V]
+	size int64
+}
+
+// newLRU returns an [lru] holding up to max entries and tracking their size
+// with sizer, or nil if there is neither a limit nor a sizer.
+func newLRU[K comparable, V any](max int, sizer func(V) int) *lru[K, V] {
+	if max <= 0 && sizer == nil {
+		return nil
+	}
+	return &lru[K, V]{max: max, sizer: sizer}
+}
+
+// add tracks the cached result of call, and returns the least recently used
+// entries beyond the capacity, which must be evicted from the group.
+func (l *lru[K, V]) add(key K, call *call[V]) []*lruEntry[K, V] {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if call.elem != nil {
+		return nil
+	}
+	entry := &lruEntry[K, V]{key: key, call: call}
+	if l.sizer != nil {
+		entry.size = int64(l.sizer(call.value))
+		l.size.Add(entry.size)
+	}
+	call.elem = l.list.PushFront(entry)
+	var victims []*lruEntry[K, V]
+	for l.max > 0 && l.list.Len() > l.max {
+		victim := l.list.Back().Value.(*lruEntry[K, V])
+		l.unlink(victim)
+		victims = append(victims, victim)
+	}
+	return victims
+}
+
+// touch marks the cached result of call as the most recently used.
+func (l *lru[K, V]) touch(call *call[V]) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if call.elem != nil {
+		l.list.MoveToFront(call.elem)
+	}
+}
+
+// remove stops tracking the cached result of call, once it was evicted.
+func (l *lru[K, V]) remove(call *call[V]) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if call.elem != nil {
+		l.unlink(call.elem.Value.(*lruEntry[K, V]))
+	}
+}
+
+// unlink removes entry from the lru, which must be locked.
+func (l *lru[K, V]) unlink(entry *lruEntry[K, V]) {
+	l.list.Remove(entry.call.elem)
+	entry.call.elem = nil
+	l.size.Add(-entry.size)
+}
+
+// cache tracks call, which was stored for key with a cached result, and
+// evicts the least recently used results beyond the capacity of the group.
+func (g *Group[K, V]) cache(key K, call *call[V]) {
+	for _, victim := range g.lru.add(key, call) {
+		if g.store().CompareAndDelete(victim.key, victim.call) {
+			g.evicted(victim.key, victim.call, Capacity)
+		}
+	}
+	// The call may have been removed before being tracked.
+	if c, ok := g.store().Load(key); !ok || c != call {
+		g.lru.remove(call)
+	}
+}
+
+// evicted is called once the cached result of call was removed from the
+// group for reason.
+func (g *Group[K, V]) evicted(key K, call *call[V], reason EvictReason) {
+	g.lru.remove(call)
+	g.hooks.onEvict(key, call, reason)
+}
+
+// WithSizer tracks the approximate total size of the cached results of the
+// group, as returned by [Group.ApproxSize], with sizer returning the size of
+// a value, for instance in bytes. This helps tuning [WithMaxEntries].
+//
+// sizer is called once for every result cached, and must be cheap. The type
+// parameter of sizer must match the value type of the group given the
+// option.
+func WithSizer[V any](sizer func(V) int) Option {
+	return func(o *options) { o.sizer = sizer }
+}
+
+// ApproxSize returns the total size of the cached results of the group, as
+// reported by the function given to [WithSizer], or 0 without it. Expired
+// results count until they are evicted.
+//
+// ApproxSize is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) ApproxSize() int64 {
+	if g.lru == nil {
+		return 0
+	}
+	return g.lru.size.Load()
+}
@@ -0,0 +1,89 @@
+package inflight
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxEntries(t *testing.T) {
+	opt, evictions := recordEvictions()
+	g := NewWithTTL[string, string](time.Minute, WithMaxEntries(2), opt)
+
+	mustDo(t, g, "a", "1")
+	mustDo(t, g, "b", "2")
+	mustDo(t, g, "c", "3")
+	require.Equal(t, []string{"b", "c"}, slices.Sorted(g.Keys()))
+	require.Equal(t, []eviction{{"a", "1", Capacity}}, evictions())
+
+	// Using b makes c the least recently used.
+	mustDo(t, g, "b", "2")
+	mustDo(t, g, "d", "4")
+	require.Equal(t, []string{"b", "d"}, slices.Sorted(g.Keys()))
+
+	// So does peeking at it.
+	_, ok := g.Peek("b")
+	require.True(t, ok)
+	mustDo(t, g, "e", "5")
+	require.Equal(t, []string{"b", "e"}, slices.Sorted(g.Keys()))
+
+	// Forgotten results free their slot.
+	g.Forget("b")
+	mustDo(t, g, "f", "6")
+	require.Equal(t, []string{"e", "f"}, slices.Sorted(g.Keys()))
+}
+
+func TestWithMaxEntriesInFlight(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute, WithMaxEntries(1))
+	mustDo(t, g, "a", "1")
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("b", func() (string, error) {
+			close(started)
+			<-block
+			return "2", nil
+		})
+	}()
+	<-started
+
+	// The call in flight is neither counted nor evicted.
+	g.Set("c", "3")
+	require.Equal(t, []string{"b", "c"}, slices.Sorted(g.Keys()))
+
+	close(block)
+	<-done
+	require.Equal(t, []string{"b"}, slices.Sorted(g.Keys()))
+}
+
+func TestWithSizer(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute, WithSizer(func(v string) int { return len(v) }), WithMaxEntries(2))
+	require.Zero(t, g.ApproxSize())
+
+	mustDo(t, g, "a", "1")
+	mustDo(t, g, "b", "22")
+	require.Equal(t, int64(3), g.ApproxSize())
+
+	// Evicting a for capacity.
+	mustDo(t, g, "c", "333")
+	require.Equal(t, int64(5), g.ApproxSize())
+
+	// Replacing c.
+	g.Set("c", "4444")
+	require.Equal(t, int64(6), g.ApproxSize())
+
+	g.Forget("b")
+	require.Equal(t, int64(4), g.ApproxSize())
+	g.Reset()
+	require.Zero(t, g.ApproxSize())
+
+	// Without sizer.
+	var g2 Group[string, string]
+	g2.Set("a", "1")
+	require.Zero(t, g2.ApproxSize())
+}
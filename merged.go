@@ -0,0 +1,138 @@
+package inflight
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// mergedContext tracks the callers of a call interested in its result, to
+// cancel the context of its function once all of them are gone, see
+// [WithMergedContext].
+//
+// The leader is registered from the start, see [newMergedContext].
+type mergedContext struct {
+	mu       sync.Mutex
+	n        int                // number of callers whose context is not done.
+	canceled bool               // whether n dropped to zero.
+	cancel   context.CancelFunc // cancels the context of the function, once started.
+	latest   time.Time          // latest deadline of the callers, see [mergedContext.deadline].
+	forever  bool               // whether a caller has no deadline.
+	grace    time.Duration      // see [WithCancelGrace].
+	timer    *time.Timer        // cancels the context once the grace period elapses.
+	graces   int                // number of grace periods started, to ignore stale timers.
+}
+
+// newMergedContext creates a [mergedContext] whose leader is registered, see
+// [mergedContext.watch], canceled grace after all the callers are gone.
+func newMergedContext(grace time.Duration) *mergedContext {
+	return &mergedContext{n: 1, grace: grace}
+}
+
+// watch unregisters the leader once its ctx is done. The returned function
+// stops watching ctx, once the leader got the result.
+func (m *mergedContext) watch(ctx context.Context) (stop func() bool) {
+	if m == nil {
+		return func() bool { return false }
+	}
+	m.mu.Lock()
+	m.observe(ctx)
+	m.mu.Unlock()
+	return context.AfterFunc(ctx, m.release)
+}
+
+// add registers a caller interested in the result of the call until ctx is
+// done, and reports whether it succeeded. It fails if all the callers are
+// already gone, in which case the function is doomed to be canceled.
+//
+// The returned function unregisters the caller, once it got the result.
+func (m *mergedContext) add(ctx context.Context) (stop func() bool, ok bool) {
+	if m == nil {
+		return func() bool { return false }, true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.canceled {
+		return nil, false
+	}
+	if m.n++; m.timer != nil {
+		// Joined within the grace period.
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.observe(ctx)
+	return context.AfterFunc(ctx, m.release), true
+}
+
+// observe records the deadline of ctx, the context of a caller.
+// m.mu must be held.
+func (m *mergedContext) observe(ctx context.Context) {
+	d, ok := ctx.Deadline()
+	switch {
+	case !ok:
+		m.forever = true
+	case d.After(m.latest):
+		m.latest = d
+	}
+}
+
+// deadline returns the time the context of the function is canceled at,
+// unless its callers are gone earlier: the latest deadline of the callers,
+// or the zero time if one of them has no deadline.
+func (m *mergedContext) deadline() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.forever {
+		return time.Time{}
+	}
+	return m.latest
+}
+
+// release unregisters a caller whose context is done, and cancels the
+// context of the function if it was the last one, once the grace period
+// elapses.
+func (m *mergedContext) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.n--; m.n > 0 {
+		return
+	}
+	if m.grace > 0 {
+		m.graces++
+		grace := m.graces
+		m.timer = time.AfterFunc(m.grace, func() { m.expire(grace) })
+		return
+	}
+	m.abort()
+}
+
+// expire cancels the context of the function once the given grace period
+// elapsed, unless a caller joined in the meantime.
+func (m *mergedContext) expire(grace int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.n == 0 && !m.canceled && m.graces == grace {
+		m.abort()
+	}
+}
+
+// abort cancels the context of the function. m.mu must be held.
+func (m *mergedContext) abort() {
+	m.canceled = true
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// context returns the context of the function, carrying the values of ctx,
+// the context of the leader, but canceled once all the callers are gone.
+func (m *mergedContext) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancel = cancel
+	if m.canceled {
+		cancel()
+	}
+	return fctx, cancel
+}
@@ -0,0 +1,171 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMergedContext(t *testing.T) {
+	g := New[string, string](WithMergedContext())
+
+	type ctxKey struct{}
+	var calls atomic.Int32
+	var canceledAt atomic.Int64
+	start := time.Now()
+	fn := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		require.Equal(t, "leader", ctx.Value(ctxKey{}))
+		<-ctx.Done()
+		canceledAt.Store(int64(time.Since(start)))
+		return "", ctx.Err()
+	}
+
+	// Staggered cancellations: the leader first, the last waiter last.
+	var wg sync.WaitGroup
+	for i, d := range []time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 60 * time.Millisecond} {
+		ctx, cancel := context.WithTimeout(t.Context(), d)
+		defer cancel()
+		if i == 0 {
+			ctx = context.WithValue(ctx, ctxKey{}, "leader")
+		}
+		wg.Go(func() {
+			_, _, err := g.DoContext(ctx, "key", fn)
+			switch i {
+			case 0:
+				// The leader gets the error of the function.
+				require.ErrorIs(t, err, context.Canceled)
+			case 1:
+				require.ErrorIs(t, err, context.DeadlineExceeded)
+			default:
+				// The last waiter may get the error of the function too,
+				// since its departure completes the call.
+				require.True(t, errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+			}
+		})
+		time.Sleep(time.Millisecond) // Ensure the leader is the first caller.
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), calls.Load())
+	require.GreaterOrEqual(t, time.Duration(canceledAt.Load()), 60*time.Millisecond)
+}
+
+func TestWithMergedContextLeaderGetsResult(t *testing.T) {
+	g := New[string, string](WithMergedContext())
+
+	var calls atomic.Int32
+	fn := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return "bar", nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	leader := make(chan error, 1)
+	go func() {
+		v, _, err := g.DoContext(ctx, "key", fn)
+		if err == nil && v != "bar" {
+			err = context.Canceled
+		}
+		leader <- err
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the leader is running.
+
+	waiter := make(chan string, 1)
+	go func() {
+		v, _, err := g.DoContext(t.Context(), "key", fn)
+		require.NoError(t, err)
+		waiter <- v
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the waiter joined.
+
+	// The function keeps running for the waiter, and the leader gets its
+	// result even though its own context is done.
+	cancel()
+	require.Equal(t, "bar", <-waiter)
+	require.NoError(t, <-leader)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestWithMergedContextAllGone(t *testing.T) {
+	g := New[string, string](WithMergedContext())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, _, err := g.DoContext(ctx, "key", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The next call executes again.
+	v, _, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) { return "bar", nil })
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+}
+
+func TestWithCancelGrace(t *testing.T) {
+	const grace = 100 * time.Millisecond
+	g := New[string, string](WithMergedContext(), WithCancelGrace(grace))
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	block := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		close(started)
+		select {
+		case <-block:
+			return "bar", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	leader := make(chan error)
+	go func() {
+		_, _, err := g.DoContext(ctx, "key", fn)
+		leader <- err
+	}()
+	<-started
+	cancel()
+
+	// A waiter arriving within the grace period reuses the running call.
+	time.Sleep(grace / 4)
+	waiter := make(chan string)
+	go func() {
+		v, shared, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) { panic("unreachable") })
+		require.NoError(t, err)
+		require.True(t, shared)
+		waiter <- v
+	}()
+	require.Eventually(t, func() bool { return g.Waiters("key") == 2 }, time.Second, time.Millisecond)
+	time.Sleep(grace) // The grace period elapsed, but the waiter is still there.
+	close(block)
+	require.Equal(t, "bar", <-waiter)
+	require.NoError(t, <-leader)
+	require.Equal(t, int32(1), calls.Load())
+
+	// Without any caller joining, the function is canceled once the grace
+	// period elapses.
+	ctx, cancel = context.WithCancel(t.Context())
+	cancel()
+	start := time.Now()
+	_, _, err := g.DoContext(ctx, "other", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.GreaterOrEqual(t, time.Since(start), grace)
+}
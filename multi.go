@@ -0,0 +1,219 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoResult is returned by [Group.DoMulti] for a key which is missing from
+// the map returned by its function.
+var ErrNoResult = errors.New("inflight: no result for key")
+
+// DoMulti resolves several keys at once, deduplicating each of them against
+// concurrent calls of the group.
+//
+// Keys already in flight, from any other call of the group, are awaited
+// rather than fetched again, and keys with a cached result are served from
+// the cache. fn is called once with the remaining keys, and its results are
+// distributed to all the waiters of those keys, including concurrent
+// [Group.Do] callers for individual keys. A key missing from the map returned
+// by fn gets [ErrNoResult], and every key gets the error returned by fn, if
+// any. A key whose call can't be joined because of [WithMaxWaiters] gets
+// [ErrTooManyWaiters], and every key gets [ErrClosed] once the group is
+// closed.
+//
+// DoMulti returns the value of every key that succeeded, and the error of
+// every key that failed. fn is not called if no key is missing.
+//
+// DoMulti is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoMulti(keys []K, fn func(missing []K) (map[K]V, error)) (map[K]V, map[K]error) {
+	ctx := context.Background()
+	start := time.Now()
+	calls := make(map[K]*call[V], len(keys))
+	errs := make(map[K]error)
+	var missing []K
+	fetched := make(map[K]bool)
+	for _, key := range keys {
+		if _, ok := calls[key]; ok || errs[key] != nil {
+			continue
+		}
+		g.stats.calls.Add(1)
+		if g.closed.Load() {
+			errs[key] = ErrClosed
+			continue
+		}
+		call, fetch, err := g.joinMulti(ctx, key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		if fetch {
+			missing = append(missing, key)
+			fetched[key] = true
+		}
+		defer call.leave()
+		calls[key] = call
+	}
+
+	if len(missing) > 0 {
+		g.fetch(calls, missing, fn)
+	}
+
+	values := make(map[K]V, len(calls))
+	for key, call := range calls {
+		var (
+			value V
+			err   error
+		)
+		if fetched[key] {
+			value, err = call.result() // Completed by fetch, not shared.
+		} else {
+			value, _, err = g.wait(ctx, key, call, nil, callOptions{})
+		}
+		if observe := g.opts.waitHistogram; observe != nil {
+			observe(time.Since(start))
+		}
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		values[key] = value
+	}
+	return values, errs
+}
+
+// joinMulti returns the call for key joined by a caller of [Group.DoMulti],
+// or a new call stored for key if none can be joined, in which case it
+// reports true: the caller must then complete the call.
+func (g *Group[K, V]) joinMulti(ctx context.Context, key K) (*call[V], bool, error) {
+	call, loaded := g.load(key)
+	if !loaded {
+		return call, true, nil
+	}
+	if !call.join(false, 0, g.opts.maxWaiters) {
+		return nil, false, ErrTooManyWaiters
+	}
+	// Keep the function running until its result is received.
+	if _, ok := call.merged.add(ctx); !ok {
+		// All the callers of the call are gone, its function is being
+		// canceled: start a new call instead.
+		call.leave()
+		g.store().CompareAndDelete(key, call)
+		return g.joinMulti(ctx, key)
+	}
+	g.stats.shared.Add(1)
+	g.hooks.onJoin(ctx, key)
+	return call, false, nil
+}
+
+// DoBatch resolves several keys at once, each with its own function, and
+// deduplicates each of them against concurrent calls of the group, like
+// [Group.Do]: keys already in flight are awaited rather than executed again,
+// and keys with a cached result are served from the cache.
+//
+// At most maxConcurrency keys of the batch are resolved at the same time, or
+// all of them if maxConcurrency <= 0. A key awaiting the call of another
+// goroutine counts towards this limit, like a key executing its function,
+// which never holds a slot of [WithMaxConcurrency] while waiting for the
+// batch.
+//
+// DoBatch returns the value of every key that succeeded, and the error of
+// every key that failed. If a function panics, DoBatch panics with the same
+// value once every key is resolved.
+//
+// DoBatch is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoBatch(calls map[K]func() (V, error), maxConcurrency int) (map[K]V, map[K]error) {
+	var sem chan struct{}
+	if maxConcurrency > 0 {
+		sem = make(chan struct{}, maxConcurrency)
+	}
+	var (
+		mu     sync.Mutex
+		values = make(map[K]V, len(calls))
+		errs   = make(map[K]error)
+		p      any
+		wg     sync.WaitGroup
+	)
+	for key, fn := range calls {
+		// Acquire the slot of the key before spawning its goroutine, so that
+		// at most maxConcurrency goroutines are running.
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		wg.Go(func() {
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					mu.Lock()
+					p = r
+					mu.Unlock()
+				}
+			}()
+			value, _, err := g.Do(key, fn)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[key] = err
+				return
+			}
+			values[key] = value
+		})
+	}
+	wg.Wait()
+	if p != nil {
+		panic(p)
+	}
+	return values, errs
+}
+
+// fetch calls fn with the missing keys, and completes their call with its
+// results. If fn panics, the panic is propagated to every waiter.
+func (g *Group[K, V]) fetch(calls map[K]*call[V], missing []K, fn func(missing []K) (map[K]V, error)) {
+	g.stats.executions.Add(1)
+	g.running.start()
+	defer g.running.stop()
+	var dones []execution
+	for i, key := range missing {
+		if done := g.hooks.onStart(context.Background(), key); done != nil {
+			if dones == nil {
+				dones = make([]execution, len(missing))
+			}
+			dones[i] = done
+		}
+	}
+	start := time.Now()
+	execute(context.Background(), &g.opts, guardKeys(&g.hooks, missing, limit(&g.opts, func(context.Context) (map[K]V, error) { return fn(missing) })), func(values map[K]V, err error, p any) {
+		d := time.Since(start)
+		g.stats.failed(err, p)
+		for i, key := range missing {
+			var done execution
+			if dones != nil {
+				done = dones[i]
+			}
+			value, ok := values[key]
+			err := err
+			if err == nil && !ok {
+				err = ErrNoResult
+			}
+			if p == nil {
+				value, err = g.hooks.validation(value, err)
+			}
+			call := calls[key]
+			callers := call.callers.Load()
+			if call.orphaned() {
+				g.stats.orphans.Add(1)
+			}
+			call.elapsed = d
+			g.finish(key, call, value, err, p)
+			if p != nil {
+				g.hooks.onPanicked(done, p, d)
+			} else {
+				g.hooks.onFinish(done, key, callers > 1, err, d)
+			}
+		}
+	})
+}
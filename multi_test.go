@@ -0,0 +1,227 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoMulti(t *testing.T) {
+	var g Group[string, int]
+
+	var calls atomic.Int32
+	values, errs := g.DoMulti([]string{"a", "b", "a", "c"}, func(missing []string) (map[string]int, error) {
+		calls.Add(1)
+		require.ElementsMatch(t, []string{"a", "b", "c"}, missing)
+		return map[string]int{"a": 1, "b": 2}, nil
+	})
+	require.Equal(t, int32(1), calls.Load())
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, values)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs["c"], ErrNoResult)
+	require.Zero(t, g.Len())
+}
+
+func TestDoMultiErr(t *testing.T) {
+	var g Group[string, int]
+	someErr := errors.New("some error")
+	values, errs := g.DoMulti([]string{"a", "b"}, func([]string) (map[string]int, error) {
+		return nil, someErr
+	})
+	require.Empty(t, values)
+	require.ErrorIs(t, errs["a"], someErr)
+	require.ErrorIs(t, errs["b"], someErr)
+}
+
+func TestDoMultiDedup(t *testing.T) {
+	var g Group[string, int]
+
+	// Key "a" is already in flight through Do.
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, _, err := g.Do("a", func() (int, error) {
+			close(started)
+			<-block
+			return 1, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, v)
+	})
+	<-started
+
+	// Key "b" is awaited by Do while DoMulti fetches it.
+	fetching := make(chan struct{})
+	wg.Go(func() {
+		<-fetching
+		v, shared, err := g.Do("b", func() (int, error) {
+			t.Error("b must be fetched by DoMulti")
+			return 0, nil
+		})
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, 2, v)
+	})
+
+	values, errs := g.DoMulti([]string{"a", "b"}, func(missing []string) (map[string]int, error) {
+		require.Equal(t, []string{"b"}, missing)
+		close(fetching)
+		time.Sleep(20 * time.Millisecond) // Let Do join the call for "b".
+		close(block)
+		return map[string]int{"b": 2}, nil
+	})
+	require.Empty(t, errs)
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, values)
+	wg.Wait()
+}
+
+func TestDoMultiCanceled(t *testing.T) {
+	g := New[string, int](WithMergedContext())
+
+	// The function of the call for "a" ignores its context, which is
+	// canceled since its only caller is gone.
+	started := make(chan struct{})
+	block := make(chan struct{})
+	defer close(block)
+	ctx, cancel := context.WithCancel(t.Context())
+	go g.DoContext(ctx, "a", func(context.Context) (int, error) {
+		close(started)
+		<-block
+		return 0, nil
+	})
+	<-started
+	cancel()
+	time.Sleep(10 * time.Millisecond) // Ensure the cancellation is processed.
+
+	// DoMulti fetches the key instead of joining the doomed call.
+	values, errs := g.DoMulti([]string{"a"}, func(missing []string) (map[string]int, error) {
+		require.Equal(t, []string{"a"}, missing)
+		return map[string]int{"a": 1}, nil
+	})
+	require.Empty(t, errs)
+	require.Equal(t, map[string]int{"a": 1}, values)
+}
+
+func TestDoBatch(t *testing.T) {
+	var g Group[string, int]
+	someErr := errors.New("some error")
+
+	values, errs := g.DoBatch(map[string]func() (int, error){
+		"a": func() (int, error) { return 1, nil },
+		"b": func() (int, error) { return 2, nil },
+		"c": func() (int, error) { return 0, someErr },
+	}, 0)
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, values)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs["c"], someErr)
+}
+
+func TestDoBatchConcurrency(t *testing.T) {
+	var g Group[int, int]
+
+	const n = 2
+	var running, maxRunning atomic.Int32
+	calls := make(map[int]func() (int, error))
+	for i := range 10 {
+		calls[i] = func() (int, error) {
+			cur := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := maxRunning.Load()
+				if cur <= old || maxRunning.CompareAndSwap(old, cur) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return i, nil
+		}
+	}
+	values, errs := g.DoBatch(calls, n)
+	require.Empty(t, errs)
+	require.Len(t, values, 10)
+	require.LessOrEqual(t, maxRunning.Load(), int32(n))
+}
+
+func TestDoBatchMaxConcurrency(t *testing.T) {
+	g := New[int, int](WithMaxConcurrency(2))
+
+	// The keys waiting for the batch don't hold the slots of the group.
+	block := make(chan struct{})
+	calls := make(map[int]func() (int, error))
+	for i := range 10 {
+		calls[i] = func() (int, error) {
+			<-block
+			return i, nil
+		}
+	}
+	batched := make(chan struct{})
+	go func() {
+		defer close(batched)
+		values, errs := g.DoBatch(calls, 1)
+		require.Empty(t, errs)
+		require.Len(t, values, 10)
+	}()
+	time.Sleep(10 * time.Millisecond) // Ensure the batch is running.
+
+	v, _, err := g.Do(-1, func() (int, error) { return -1, nil })
+	require.NoError(t, err)
+	require.Equal(t, -1, v)
+	close(block)
+	<-batched
+}
+
+func TestDoBatchDedup(t *testing.T) {
+	var g Group[string, int]
+
+	// A key in flight from a single Do call is awaited by the batches.
+	block := make(chan struct{})
+	var executions atomic.Int32
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, shared, err := g.Do("a", func() (int, error) {
+			executions.Add(1)
+			<-block
+			return 1, nil
+		})
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, 1, v)
+	})
+	time.Sleep(10 * time.Millisecond) // Ensure the call is in flight.
+
+	// Overlapping batches share their keys.
+	fn := func(v int) func() (int, error) {
+		return func() (int, error) {
+			executions.Add(1)
+			<-block
+			return v, nil
+		}
+	}
+	for range 2 {
+		wg.Go(func() {
+			values, errs := g.DoBatch(map[string]func() (int, error){"a": fn(-1), "b": fn(2)}, 2)
+			require.Empty(t, errs)
+			require.Equal(t, map[string]int{"a": 1, "b": 2}, values)
+		})
+	}
+	time.Sleep(10 * time.Millisecond) // Ensure the batches joined the calls.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(2), executions.Load())
+}
+
+func TestDoBatchPanic(t *testing.T) {
+	var g Group[string, int]
+	require.PanicsWithValue(t, "boom", func() {
+		g.DoBatch(map[string]func() (int, error){
+			"a": func() (int, error) { return 1, nil },
+			"b": func() (int, error) { panic("boom") },
+		}, 0)
+	})
+}
@@ -0,0 +1,52 @@
+package inflight
+
+import "context"
+
+// NamespacedKey is the key of a [Group] shared by several [Namespace] values:
+// it composes the name of a namespace with a key of that namespace.
+//
+// Like any key of a Group, it is comparable as long as K is.
+type NamespacedKey[K comparable] struct {
+	Namespace string
+	Key       K
+}
+
+// Namespace is a view of a [Group] which isolates its keys from the other
+// namespaces of the group. This allows several logical caches to share a
+// single Group, along with its options and stats, without key collisions.
+//
+// Namespace is safe for concurrent use by multiple goroutines.
+type Namespace[K comparable, V any] struct {
+	g    *Group[NamespacedKey[K], V]
+	name string
+}
+
+// NewNamespace returns the namespace of g called name. Namespaces with the
+// same name share their keys, calls for the same key in different namespaces
+// are independent.
+//
+// g must be keyed by [NamespacedKey], with K the type of the keys of the
+// namespace and V the type of the results of the functions.
+func NewNamespace[K comparable, V any](g *Group[NamespacedKey[K], V], name string) *Namespace[K, V] {
+	return &Namespace[K, V]{g: g, name: name}
+}
+
+// key returns the key of the underlying group for key.
+func (n *Namespace[K, V]) key(key K) NamespacedKey[K] {
+	return NamespacedKey[K]{Namespace: n.name, Key: key}
+}
+
+// Do is like [Group.Do], for the key of the namespace.
+func (n *Namespace[K, V]) Do(key K, fn func() (V, error)) (V, bool, error) {
+	return n.g.Do(n.key(key), fn)
+}
+
+// DoContext is like [Group.DoContext], for the key of the namespace.
+func (n *Namespace[K, V]) DoContext(ctx context.Context, key K, fn func(context.Context) (V, error)) (V, bool, error) {
+	return n.g.DoContext(ctx, n.key(key), fn)
+}
+
+// Forget is like [Group.Forget], for the key of the namespace.
+func (n *Namespace[K, V]) Forget(key K) {
+	n.g.Forget(n.key(key))
+}
@@ -0,0 +1,62 @@
+package inflight
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	var g Group[NamespacedKey[string], string]
+	users := NewNamespace(&g, "users")
+	groups := NewNamespace(&g, "groups")
+
+	var userCalls, groupCalls atomic.Int32
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			v, _, err := users.Do("key", func() (string, error) {
+				userCalls.Add(1)
+				time.Sleep(20 * time.Millisecond)
+				return "user", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "user", v)
+		})
+		wg.Go(func() {
+			v, _, err := groups.Do("key", func() (string, error) {
+				groupCalls.Add(1)
+				time.Sleep(20 * time.Millisecond)
+				return "group", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "group", v)
+		})
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), userCalls.Load())
+	require.Equal(t, int32(1), groupCalls.Load())
+	require.Equal(t, int64(2), g.Stats().Executions)
+}
+
+func TestNamespaceForget(t *testing.T) {
+	g := NewWithTTL[NamespacedKey[string], string](time.Minute)
+	users := NewNamespace(g, "users")
+	groups := NewNamespace(g, "groups")
+
+	_, _, err := users.Do("key", func() (string, error) { return "user", nil })
+	require.NoError(t, err)
+	_, _, err = groups.Do("key", func() (string, error) { return "group", nil })
+	require.NoError(t, err)
+
+	users.Forget("key")
+	_, ok := g.Peek(NamespacedKey[string]{Namespace: "users", Key: "key"})
+	require.False(t, ok)
+	v, ok := g.Peek(NamespacedKey[string]{Namespace: "groups", Key: "key"})
+	require.True(t, ok)
+	require.Equal(t, "group", v)
+}
@@ -0,0 +1,54 @@
+package inflight
+
+// WithKeyNormalizer makes the group normalize every key with normalize before
+// looking up its call, so that keys normalized to the same value share their
+// calls and cached results. For instance, strings.ToLower matches string keys
+// case-insensitively, without normalizing them at every call site.
+//
+// Every method of the group taking a key normalizes it, including
+// [Group.Forget], so that forgetting any form of a key forgets its normalized
+// form. The keys reported by [Group.Keys], [Group.TopKeys] and to the
+// eviction callback (see [WithEvictionCallback]) are normalized, while the
+// ones reported to an [Observer] are the ones given by the callers.
+//
+// normalize must be deterministic and fast, since it is called on every
+// lookup. The type parameter of normalize must match the key type of the
+// group given the option.
+func WithKeyNormalizer[K comparable](normalize func(K) K) Option {
+	return func(o *options) { o.keyNormalizer = normalize }
+}
+
+// normalizedStore is a [store] normalizing its keys before passing them to
+// the underlying store, see [WithKeyNormalizer].
+type normalizedStore[K comparable, V any] struct {
+	s         store[K, V]
+	normalize func(K) K
+}
+
+func (n normalizedStore[K, V]) Load(key K) (V, bool) {
+	return n.s.Load(n.normalize(key))
+}
+
+func (n normalizedStore[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	return n.s.LoadOrStore(n.normalize(key), value)
+}
+
+func (n normalizedStore[K, V]) LoadAndDelete(key K) (V, bool) {
+	return n.s.LoadAndDelete(n.normalize(key))
+}
+
+func (n normalizedStore[K, V]) Swap(key K, value V) (V, bool) {
+	return n.s.Swap(n.normalize(key), value)
+}
+
+func (n normalizedStore[K, V]) CompareAndSwap(key K, old, new V) bool {
+	return n.s.CompareAndSwap(n.normalize(key), old, new)
+}
+
+func (n normalizedStore[K, V]) CompareAndDelete(key K, old V) bool {
+	return n.s.CompareAndDelete(n.normalize(key), old)
+}
+
+func (n normalizedStore[K, V]) Range(yield func(K, V) bool) {
+	n.s.Range(yield)
+}
@@ -0,0 +1,60 @@
+package inflight
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func normalizeKey(key string) string { return strings.ToLower(strings.TrimSpace(key)) }
+
+func TestWithKeyNormalizer(t *testing.T) {
+	g := New[string, string](WithKeyNormalizer(normalizeKey))
+
+	var calls atomic.Int32
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, key := range []string{"foo", "Foo ", " FOO", "fOo"} {
+		wg.Go(func() {
+			v, _, err := g.Do(key, func() (string, error) {
+				calls.Add(1)
+				<-block
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "bar", v)
+		})
+	}
+	time.Sleep(20 * time.Millisecond) // Ensure the callers joined.
+	require.True(t, g.InFlight("FOO"))
+	require.Equal(t, []string{"foo"}, slices.Collect(g.Keys()))
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestWithKeyNormalizerForget(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute, WithKeyNormalizer(normalizeKey))
+	mustDo(t, g, "Foo", "bar")
+
+	v, ok := g.Peek(" foo ")
+	require.True(t, ok)
+	require.Equal(t, "bar", v)
+
+	g.Forget("FOO")
+	_, ok = g.Peek("foo")
+	require.False(t, ok)
+}
+
+func TestWithKeyNormalizerStore(t *testing.T) {
+	var s syncMapStore[string]
+	g := NewWithStore[string, string](&s, WithKeyNormalizer(normalizeKey), WithTTL(time.Minute))
+	mustDo(t, g, "Foo", "bar")
+	_, ok := s.Load("foo")
+	require.True(t, ok)
+}
@@ -0,0 +1,139 @@
+package inflight
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+// Observer receives the lifecycle events of the calls of a [Group], to plug
+// metrics or tracing into it. See [WithObserver].
+//
+// The methods of an Observer are called synchronously by the goroutines
+// calling the group, without holding any internal lock: they may call the
+// group again, but should return quickly.
+type Observer[K comparable, V any] interface {
+	// OnStart is called by the leader of a call, right before it executes
+	// its function.
+	OnStart(key K)
+	// OnJoin is called by a caller reusing the result of another call,
+	// either in flight or cached.
+	OnJoin(key K)
+	// OnFinish is called by the leader of a call once its function returns
+	// err after executing for d. shared is the value returned to the leader.
+	// It is not called if the function panics, unless [WithPanicToError]
+	// is set.
+	OnFinish(key K, shared bool, err error, d time.Duration)
+}
+
+// DropObserver is implemented by an [Observer] which also wants to be
+// notified of the results dropped because of [WithChanTimeout].
+type DropObserver[K comparable] interface {
+	// OnDrop is called by a caller of [Group.DoChan] or [Group.DoChanContext]
+	// which stopped waiting on the call for key once the timeout elapsed.
+	OnDrop(key K)
+}
+
+// StartObserver is implemented by an [Observer] which also wants to know the
+// context a function is executed with, for instance to link the span of the
+// execution to the trace of the caller which led it.
+type StartObserver[K comparable] interface {
+	// OnStartContext is called right after OnStart, with the context passed
+	// to the function. It carries the values of the context of the leader,
+	// see [Group.DoContext].
+	OnStartContext(ctx context.Context, key K)
+}
+
+// JoinObserver is implemented by an [Observer] which also wants to know the
+// context of the callers reusing the result of another call, for instance to
+// link their trace to the one of the execution they joined.
+type JoinObserver[K comparable] interface {
+	// OnJoinContext is called right after OnJoin, with the context of the
+	// caller joining the call.
+	OnJoinContext(ctx context.Context, key K)
+}
+
+// ExecutionObserver is implemented by an [Observer] which also wants to tell
+// apart the executions of the functions of a same key, which may overlap, for
+// instance with [Group.DoFresh], or once a leader steps down and a waiter
+// executes its function again.
+type ExecutionObserver[K comparable] interface {
+	// OnExecute is called right after OnStart and OnStartContext, with the
+	// context passed to the function. The returned function, if not nil,
+	// is called exactly once when this execution ends, right after
+	// OnFinish, with the same arguments. Unlike OnFinish, it is also called
+	// if the function panics, with a [*PanicError] as err.
+	OnExecute(ctx context.Context, key K) (done func(shared bool, err error, d time.Duration))
+}
+
+// WithObserver registers o to receive the lifecycle events of the calls of
+// the group: exactly one OnStart and one OnFinish per execution of a function,
+// and one OnJoin per caller sharing its result.
+//
+// The type parameters of o must match the ones of the group given the option.
+func WithObserver[K comparable, V any](o Observer[K, V]) Option {
+	return func(opts *options) { opts.observer = o }
+}
+
+// execution is called once an execution of a function ends, see
+// [ExecutionObserver].
+type execution func(shared bool, err error, d time.Duration)
+
+// onStart calls [Observer.OnStart] if an observer is registered, then
+// [StartObserver.OnStartContext] and [KeyStringerObserver.OnExecuteString] or
+// [ExecutionObserver.OnExecute] with ctx if it implements them. The returned execution must be passed to
+// [hooks.onFinish] or [hooks.onPanicked] once the function returns.
+func (h *hooks[K, V]) onStart(ctx context.Context, key K) execution {
+	if h.observer == nil {
+		return nil
+	}
+	h.observer.OnStart(key)
+	if o, ok := h.observer.(StartObserver[K]); ok {
+		o.OnStartContext(ctx, key)
+	}
+	switch o := h.observer.(type) {
+	case KeyStringerObserver[K]:
+		return o.OnExecuteString(ctx, key, h.format(key))
+	case ExecutionObserver[K]:
+		return o.OnExecute(ctx, key)
+	}
+	return nil
+}
+
+// onJoin calls [Observer.OnJoin] if an observer is registered, then
+// [JoinObserver.OnJoinContext] with ctx if it implements it.
+func (h *hooks[K, V]) onJoin(ctx context.Context, key K) {
+	if h.observer == nil {
+		return
+	}
+	h.observer.OnJoin(key)
+	if o, ok := h.observer.(JoinObserver[K]); ok {
+		o.OnJoinContext(ctx, key)
+	}
+}
+
+// onFinish calls [Observer.OnFinish] if an observer is registered, then ends
+// the execution e of its function, if any.
+func (h *hooks[K, V]) onFinish(e execution, key K, shared bool, err error, d time.Duration) {
+	if h.observer != nil {
+		h.observer.OnFinish(key, shared, err, d)
+	}
+	if e != nil {
+		e(shared, err, d)
+	}
+}
+
+// onPanicked ends the execution e of a function which panicked with p after
+// executing for d, if any.
+func (h *hooks[K, V]) onPanicked(e execution, p any, d time.Duration) {
+	if e != nil {
+		e(false, &PanicError{Value: p, Stack: debug.Stack()}, d)
+	}
+}
+
+// onDrop calls [DropObserver.OnDrop] if the registered observer implements it.
+func (h *hooks[K, V]) onDrop(key K) {
+	if o, ok := h.observer.(DropObserver[K]); ok {
+		o.OnDrop(key)
+	}
+}
@@ -0,0 +1,167 @@
+package inflight
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver is an [Observer] recording the events it receives.
+type recordingObserver[K comparable, V any] struct {
+	mu       sync.Mutex
+	starts   []K
+	joins    []K
+	finishes []K
+	shared   []bool
+	errs     []error
+	d        []time.Duration
+	drops    []K
+	ctxs     []context.Context
+	joinCtxs []context.Context
+}
+
+func (o *recordingObserver[K, V]) OnStart(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.starts = append(o.starts, key)
+}
+
+func (o *recordingObserver[K, V]) OnJoin(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.joins = append(o.joins, key)
+}
+
+func (o *recordingObserver[K, V]) OnFinish(key K, shared bool, err error, d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.finishes = append(o.finishes, key)
+	o.shared = append(o.shared, shared)
+	o.errs = append(o.errs, err)
+	o.d = append(o.d, d)
+}
+
+func (o *recordingObserver[K, V]) OnDrop(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.drops = append(o.drops, key)
+}
+
+func (o *recordingObserver[K, V]) OnStartContext(ctx context.Context, key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ctxs = append(o.ctxs, ctx)
+}
+
+func (o *recordingObserver[K, V]) OnJoinContext(ctx context.Context, key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.joinCtxs = append(o.joinCtxs, ctx)
+}
+
+func TestWithObserver(t *testing.T) {
+	var o recordingObserver[string, string]
+	g := New[string, string](WithObserver[string, string](&o))
+
+	const n = 8
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			g.Do("key", func() (string, error) {
+				<-block
+				time.Sleep(10 * time.Millisecond)
+				return "bar", nil
+			})
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+
+	require.Equal(t, []string{"key"}, o.starts)
+	require.Len(t, o.joins, n-1)
+	require.Equal(t, []string{"key"}, o.finishes)
+	require.Equal(t, []bool{true}, o.shared)
+	require.Equal(t, []error{nil}, o.errs)
+	require.GreaterOrEqual(t, o.d[0], 10*time.Millisecond)
+}
+
+func TestWithObserverReentrant(t *testing.T) {
+	var g *Group[string, string]
+	o := &reentrantObserver{do: func() { g.Do("other", func() (string, error) { return "", nil }) }}
+	g = New[string, string](WithObserver[string, string](o))
+	_, _, err := g.Do("key", func() (string, error) { return "", nil })
+	require.NoError(t, err)
+}
+
+// reentrantObserver is an [Observer] calling the group from OnStart.
+type reentrantObserver struct {
+	do    func()
+	depth int
+}
+
+func (o *reentrantObserver) OnStart(string) {
+	if o.depth++; o.depth == 1 {
+		o.do()
+	}
+}
+
+func (o *reentrantObserver) OnJoin(string)                               {}
+func (o *reentrantObserver) OnFinish(string, bool, error, time.Duration) {}
+
+func TestWithObserverTypeMismatch(t *testing.T) {
+	require.Panics(t, func() {
+		New[int, string](WithObserver[string, string](&recordingObserver[string, string]{}))
+	})
+}
+
+type callerKey struct{}
+
+func TestLeaderContextValues(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithMergedContext()}} {
+		var o recordingObserver[string, string]
+		g := New[string, string](append(opts, WithObserver[string, string](&o))...)
+
+		const n = 4
+		block := make(chan struct{})
+		seen := make(chan any, n)
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Go(func() {
+				ctx := context.WithValue(t.Context(), callerKey{}, i)
+				v, _, err := g.DoContext(ctx, "key", func(ctx context.Context) (string, error) {
+					seen <- ctx.Value(callerKey{})
+					<-block
+					return "bar", nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, "bar", v)
+			})
+			if i == 0 {
+				// Ensure the first caller leads the call.
+				require.Equal(t, 0, <-seen)
+			}
+		}
+		time.Sleep(10 * time.Millisecond) // Ensure the waiters joined.
+		close(block)
+		wg.Wait()
+
+		// fn only sees the values of the leader's context, which the
+		// observer gets too.
+		require.Empty(t, seen)
+		require.Len(t, o.ctxs, 1)
+		require.Equal(t, 0, o.ctxs[0].Value(callerKey{}))
+
+		// The observer gets the contexts of the other callers on join.
+		var joined []any
+		for _, ctx := range o.joinCtxs {
+			joined = append(joined, ctx.Value(callerKey{}))
+		}
+		require.ElementsMatch(t, []any{1, 2, 3}, joined)
+	}
+}
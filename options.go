@@ -0,0 +1,616 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// Option configures a [Group] created with [New].
+type Option func(*options)
+
+// options holds the configuration of a [Group].
+// The zero value disables every optional behavior.
+type options struct {
+	ttl    time.Duration // see [WithTTL].
+	errTTL time.Duration // see [WithErrorTTL].
+	jitter float64       // see [WithTTLJitter].
+
+	staleAfter time.Duration // see [WithStaleWhileRevalidate].
+
+	panicToError bool // see [WithPanicToError].
+	errorWrap    bool // see [WithErrorWrap].
+	maxWaiters   int  // see [WithMaxWaiters].
+	maxEntries   int  // see [WithMaxEntries].
+	disabled     bool // see [WithDisabled].
+
+	errorFailover bool                                              // see [WithErrorFailover].
+	aggregate     bool                                              // see [WithAggregateErrors].
+	joinPolicy    func(age time.Duration, ctx context.Context) bool // see [WithJoinPolicy].
+
+	callTimeout   time.Duration         // see [WithCallTimeout].
+	mergedContext bool                  // see [WithMergedContext].
+	cancelGrace   time.Duration         // see [WithCancelGrace].
+	waitHistogram func(d time.Duration) // see [WithWaitHistogram].
+	sem           chan struct{}         // see [WithMaxConcurrency].
+	chanTimeout   time.Duration         // see [WithChanTimeout].
+	janitor       time.Duration         // see [WithJanitor].
+	autoForget    time.Duration         // see [WithAutoForget].
+	sync          *Synchronizer         // see [WithSynchronizer].
+	background    func(task func())     // see [WithBackgroundLeader].
+	clock         Clock                 // see [WithClock].
+
+	// Generic options, resolved into [hooks] by [New].
+	observer         any // see [WithObserver].
+	keyHasher        any // see [WithKeyHasher].
+	keyNormalizer    any // see [WithKeyNormalizer].
+	keyStringer      any // see [WithKeyStringer].
+	evictionCallback any // see [WithEvictionCallback].
+	sizer            any // see [WithSizer].
+	valueCloner      any // see [WithValueCloner].
+	admission        any // see [WithAdmission].
+	panicHandler     any // see [WithPanicHandler].
+	fallback         any // see [WithFallback].
+	validator        any // see [WithValidator].
+	executionKey     any // see [WithExecutionKey].
+}
+
+// WithTTL caches successful results for d once their call completes.
+// See [NewWithTTL] for the caching semantics.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) { o.ttl = d }
+}
+
+// WithErrorTTL caches error results for d once their call completes, which
+// is usually shorter than the duration given to [WithTTL]. Callers arriving
+// within d get the same error with shared=true, without executing their
+// function. This avoids hammering a failing backend.
+//
+// Context errors ([context.Canceled] and [context.DeadlineExceeded]) are
+// never cached, since they are specific to the callers of a call.
+func WithErrorTTL(d time.Duration) Option {
+	return func(o *options) { o.errTTL = d }
+}
+
+// WithTTLJitter randomizes the TTL of each cached result (see [WithTTL] and
+// [WithErrorTTL]) within ±fraction of its base value, when it is stored.
+// This spreads out the expiry of results cached at the same time, for
+// instance at startup, which would otherwise all miss together and hit the
+// backend at once.
+//
+// fraction is clamped to [0, 1], 0 disabling the jitter.
+func WithTTLJitter(fraction float64) Option {
+	return func(o *options) { o.jitter = min(max(fraction, 0), 1) }
+}
+
+// WithPanicToError recovers panics of the functions executed by the group and
+// converts them to a [*PanicError], returned to the leader and to every waiter
+// of the call. The key is removed, so a future call executes again.
+//
+// Without this option, a panic is propagated to the leader and to every waiter
+// of the call.
+func WithPanicToError() Option {
+	return func(o *options) { o.panicToError = true }
+}
+
+// WithJanitor starts a background goroutine, called the janitor, which
+// removes the expired cached results of the group every interval. Without it,
+// expired results are only evicted on the next access to their key, which
+// leaks memory for keys that are never accessed again.
+//
+// Every sweep iterates over all the keys of the group: a short interval
+// frees memory sooner at the expense of CPU time. The janitor runs until
+// [Group.Close] is called, which must be called to release the group.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) { o.janitor = interval }
+}
+
+// WithAutoForget keeps the result of a call which is not cached for d once
+// the call completes, then forgets it: the callers arriving within d get the
+// same result with shared=true, without executing their function, even if it
+// is an error. This prevents rapid retries from executing the function again
+// right after it completed, without caching its results (see [WithTTL]).
+//
+// A single timer is started for every result kept, which forgets it
+// unless it was already removed or replaced. Cached results follow their TTL
+// instead. Context errors, panics and invalidated results (see
+// [Group.Invalidate]) are never kept.
+//
+// A value of d <= 0 disables it, which is the default.
+func WithAutoForget(d time.Duration) Option {
+	return func(o *options) { o.autoForget = d }
+}
+
+// WithErrorWrap wraps the error of a call in a [*SharedError] for every caller
+// which gets it without executing the function, either by waiting on the
+// call or from the cache, while the leader gets the error unwrapped. Callers
+// can tell shared failures apart with [errors.As], for instance to log a
+// single backend failure once. [errors.Is] still matches the wrapped error.
+//
+// Errors which are not the result of the function, such as the context error
+// of a caller giving up or [ErrTooManyWaiters], are never wrapped.
+func WithErrorWrap() Option {
+	return func(o *options) { o.errorWrap = true }
+}
+
+// WithDisabled disables the deduplication of the group if disabled is true,
+// which is useful to measure the cost of the calls without it, or to compare
+// the behavior with and without it, without changing the call sites: every
+// call executes its function, with shared=false, and nothing is ever stored
+// nor cached.
+func WithDisabled(disabled bool) Option {
+	return func(o *options) { o.disabled = disabled }
+}
+
+// WithErrorFailover makes the waiters of a call whose function fails execute
+// their own function again, instead of getting the error of the leader. The
+// failed call is evicted before its error is published, so the waiters start
+// a new call together, led by one of them, and fail over quickly to a new
+// attempt in scatter-gather workloads. The leader still gets its error.
+//
+// Only the waiters given a function and whose context is not done fail over,
+// a waiter of [Group.DoContext] whose context is done gets the error of the
+// leader, or its own context error. Cached errors (see [WithErrorTTL]) are
+// shared as usual. Since every attempt returns the error to its own leader,
+// a function which keeps failing is executed at most once per caller.
+func WithErrorFailover() Option {
+	return func(o *options) { o.errorFailover = true }
+}
+
+// WithAggregateErrors makes a call whose leadership changed (see
+// [Group.DoContext]) return the errors of all its attempts, joined with
+// [errors.Join], instead of the error of its last attempt only. Each leader
+// stepping down after its context is done contributes the error its
+// function returned, followed by the error of the attempt completing the
+// call. This shows the full failure history of flaky backends during
+// failover. A call whose last attempt succeeds returns no error.
+func WithAggregateErrors() Option {
+	return func(o *options) { o.aggregate = true }
+}
+
+// WithMaxWaiters caps the number of callers which can wait on a single
+// in-flight call, besides its leader, to shed load. Extra callers get
+// [ErrTooManyWaiters] immediately instead of piling on, so that the upstream
+// can apply backpressure. Callers served by a cached result are not limited.
+//
+// A value of n <= 0 means no limit, which is the default.
+func WithMaxWaiters(n int) Option {
+	return func(o *options) { o.maxWaiters = n }
+}
+
+// WithJoinPolicy lets join decide whether a caller joins the call in flight
+// for its key, or starts a fresh one. join is given the age of the call in
+// flight, that is how long ago it started, and the context of the caller:
+// it returns true to join the call, and false to start a new one. This
+// avoids joining a call which is unlikely to complete within the deadline of
+// the caller, for instance:
+//
+//	inflight.WithJoinPolicy(func(age time.Duration, ctx context.Context) bool {
+//		deadline, ok := ctx.Deadline()
+//		return !ok || age < time.Until(deadline)
+//	})
+//
+// A fresh call replaces the call in flight for the next callers of the key,
+// while the replaced call keeps executing and serving its existing waiters,
+// see [Group.Forget]. Cached results are always used, regardless of join.
+func WithJoinPolicy(join func(age time.Duration, ctx context.Context) bool) Option {
+	return func(o *options) { o.joinPolicy = join }
+}
+
+// WithCallTimeout bounds the execution of every function by d, regardless of
+// the contexts of their callers: the context passed to the function by
+// [Group.DoContext] expires once d elapses. A function honoring its context
+// then returns [context.DeadlineExceeded], which is returned to the leader
+// and to every waiter of the call. Since context errors are never cached,
+// the next call for the key executes again.
+//
+// Only context-aware functions are bounded, the functions given to
+// [Group.Do] can't be interrupted.
+func WithCallTimeout(d time.Duration) Option {
+	return func(o *options) { o.callTimeout = d }
+}
+
+// WithMergedContext changes how the context passed to the functions given to
+// [Group.DoContext] is canceled: instead of the context of the leader, it is
+// canceled only once the contexts of all the callers of the call are done,
+// as if its deadline was the latest of their deadlines. It still carries the
+// values of the context of the leader. A function thus keeps running as long
+// as any caller still wants its result, and its result is returned to every
+// caller still waiting, including a leader whose context is done: the
+// leader never steps down.
+//
+// Callers without a context, such as the ones of [Group.Do], keep the
+// function running until it returns.
+func WithMergedContext() Option {
+	return func(o *options) { o.mergedContext = true }
+}
+
+// WithCancelGrace delays the cancellation of the merged context of a function
+// (see [WithMergedContext]) by d once the contexts of all its callers are
+// done: a caller arriving within d joins the call still running, and the
+// context is only canceled if no caller joined it once d elapsed. This avoids
+// wasting the work of a function in bursty traffic, when callers come and go.
+//
+// It has no effect without [WithMergedContext]. A value of d <= 0 cancels the
+// context right away, which is the default.
+func WithCancelGrace(d time.Duration) Option {
+	return func(o *options) { o.cancelGrace = d }
+}
+
+// WithWaitHistogram calls observe once for every call made to the group, one
+// per key for [Group.DoMulti], with the time d the caller spent from joining
+// the group to receiving its result.
+// For a leader, this is essentially the execution time of its function, for
+// a waiter it is the time it waited, and it is close to zero for a caller
+// served by a cached result. This lets callers feed a latency histogram.
+//
+// observe is called synchronously by the calling goroutine, without holding
+// any internal lock.
+func WithWaitHistogram(observe func(d time.Duration)) Option {
+	return func(o *options) { o.waitHistogram = observe }
+}
+
+// WithChanTimeout bounds the time the callers of [Group.DoChan] and
+// [Group.DoChanContext] wait for their result to d, as if their context had a
+// deadline: once d elapses, the caller stops waiting and its channel receives
+// [context.DeadlineExceeded], the result of the call is dropped for it. The
+// drop is reported to the observer of the group if it implements
+// [DropObserver].
+//
+// Since the goroutine delivering the result then returns, a channel nobody
+// reads from simply gets garbage collected, even if the call never
+// completes.
+func WithChanTimeout(d time.Duration) Option {
+	return func(o *options) { o.chanTimeout = d }
+}
+
+// WithMaxConcurrency caps the number of functions executed concurrently by the
+// group to n, across all keys, to protect a shared downstream. A leader
+// acquires a slot before executing its function and releases it once the
+// function returns, while callers joining an in-flight call or served by a
+// cached result never consume a slot.
+//
+// A leader blocked on a slot gives up once the context given to
+// [Group.DoContext] is done, as if its function returned the context error.
+//
+// A value of n <= 0 means no limit, which is the default.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.sem = nil
+		if n > 0 {
+			o.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithBackgroundLeader makes the group execute the functions in the
+// background, with submit, instead of on the goroutine of the caller starting
+// a call: every caller then waits for the result like the callers joining the
+// call, and gets shared=true. This decouples who pays the execution cost from
+// the caller which happened to arrive first, which may be the most latency
+// sensitive: like any waiter, it stops waiting once its context is done,
+// while the function keeps executing for the other callers.
+//
+// submit must run task eventually, for instance on a new goroutine with
+// func(task func()) { go task() }, or on a worker pool. The caller starting a
+// call blocks until submit returns. The function is executed with a context
+// carrying the values of the context of this caller, but never canceled by
+// it: see [WithMergedContext] to cancel it once all the callers are gone, and
+// [WithCallTimeout] to bound it. A panic of the function is propagated to the
+// callers of the call, not to the goroutine of task.
+//
+// It applies to the calls started by [Group.Do] and its variants which join
+// calls, but not to [Group.DoFresh], [Group.DoMulti], nor to the refreshes of
+// [WithStaleWhileRevalidate].
+func WithBackgroundLeader(submit func(task func())) Option {
+	return func(o *options) { o.background = submit }
+}
+
+// WithValueCloner makes the group hand a copy of the result of a call,
+// made by clone, to every caller which did not execute its function: the
+// callers joining an in-flight call, served by a cached result or calling
+// [Group.Peek]. This is meant for mutable values such as slices or maps,
+// which would otherwise be shared by all the callers: a caller sorting a
+// shared slice in place would race with the others.
+//
+// clone must make a copy deep enough for the mutations of the callers, which
+// is the responsibility of the user. The leader of a call gets the original
+// value, which is also the one cached by the group, so it must not mutate it
+// if results are cached.
+//
+// The type parameter of clone must match the value type of the group given
+// the option.
+func WithValueCloner[V any](clone func(V) V) Option {
+	return func(o *options) { o.valueCloner = clone }
+}
+
+// WithPanicHandler registers handle to observe the panics of the functions
+// executed by the group, for instance to raise an alert. handle is called by
+// the leader of a call whose function panicked, with the recovered value and
+// the stack trace of the panic, before the panic is propagated to the
+// callers or converted to an error (see [WithPanicToError]). It is called
+// once per key of the call, see [Group.DoMulti].
+//
+// handle is called without holding any internal lock, and a panic of handle
+// is recovered and ignored. The type parameter of handle must match the key
+// type of the group given the option.
+func WithPanicHandler[K comparable](handle func(key K, recovered any, stack []byte)) Option {
+	return func(o *options) { o.panicHandler = handle }
+}
+
+// guard wraps fn so that the panic handler of h is called for key if fn
+// panics, see [WithPanicHandler].
+func guard[K comparable, V, T any](h *hooks[K, V], key K, fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	if h.panic == nil {
+		return fn
+	}
+	return guardKeys(h, []K{key}, fn)
+}
+
+// guardKeys is like [guard], for a function executed for several keys.
+func guardKeys[K comparable, V, T any](h *hooks[K, V], keys []K, fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	if h.panic == nil {
+		return fn
+	}
+	return func(ctx context.Context) (T, error) {
+		returned := false
+		defer func() {
+			if returned {
+				return
+			}
+			if r := recover(); r != nil {
+				stack := debug.Stack()
+				for _, key := range keys {
+					h.onPanic(key, r, stack)
+				}
+				panic(r)
+			}
+		}()
+		value, err := fn(ctx)
+		returned = true
+		return value, err
+	}
+}
+
+// onPanic calls the panic handler of h, recovering its own panic.
+func (h *hooks[K, V]) onPanic(key K, recovered any, stack []byte) {
+	defer func() { _ = recover() }()
+	h.panic(key, recovered, stack)
+}
+
+// WithAdmission lets admit decide whether the successful result of a call for
+// key is cached, once its function returns value (see [WithTTL]): returning
+// false skips caching it, while it is still returned to the callers of the
+// call. This complements the TTL and the capacity of the group (see
+// [WithMaxEntries]) with a per-value control, for instance to avoid caching
+// oversized values.
+//
+// admit is not called for errors, nor for the values stored by [Group.Set].
+// The type parameters of admit must match the ones of the group given the
+// option.
+func WithAdmission[K comparable, V any](admit func(key K, value V) bool) Option {
+	return func(o *options) { o.admission = admit }
+}
+
+// admission marks call as stale if its successful result value must not be
+// cached, see [WithAdmission].
+func (h *hooks[K, V]) admission(key K, call *call[V], value V, err error) {
+	if err == nil && h.admit != nil && !h.admit(key, value) {
+		call.invalidate()
+	}
+}
+
+// WithFallback lets fallback convert the error of a call for key into a
+// fallback value, for instance to serve a default when a backend fails: if it
+// returns true, every caller of the call gets the returned value and a nil
+// error instead of the error. Otherwise, the error is returned as usual.
+//
+// fallback is called once per call, by the caller completing it, without
+// holding any internal lock. It is not called for panics, nor for the error
+// of a leader stepping down (see [Group.DoContext]), since a waiter executes
+// its function again. A fallback value is never cached, so the next callers
+// execute their function again, while observers (see [WithObserver]) and
+// [Group.Stats] still see the error of the function.
+//
+// The type parameters of fallback must match the ones of the group given the
+// option.
+func WithFallback[K comparable, V any](fallback func(key K, err error) (V, bool)) Option {
+	return func(o *options) { o.fallback = fallback }
+}
+
+// rescue returns the result of a call for key whose function returned value
+// and err, replacing err by a fallback value, see [WithFallback]. call is
+// then marked as stale, so the fallback value is not cached.
+func (h *hooks[K, V]) rescue(key K, call *call[V], value V, err error) (V, error) {
+	if err == nil || err == ErrFlushed || h.fallback == nil {
+		return value, err
+	}
+	fallback, ok := h.fallback(key, err)
+	if !ok {
+		return value, err
+	}
+	call.invalidate()
+	return fallback, nil
+}
+
+// WithValidator makes the leader of a call check the value returned by its
+// function with validate, before sharing or caching it: if validate returns
+// an error, the call fails with it as if the function returned it, and the
+// value is discarded. This catches invalid values, such as partially written
+// ones, which must neither reach the waiters nor be cached: the key is
+// evicted, and the next call executes its function again, unless errors are
+// cached (see [WithErrorTTL]).
+//
+// validate is only called for the values returned without error, by the
+// goroutine of the leader, once per key for [Group.DoMulti]. The type
+// parameter of validate must match the value type of the group given the
+// option.
+func WithValidator[V any](validate func(V) error) Option {
+	return func(o *options) { o.validator = validate }
+}
+
+// validated wraps fn so that its successful values are checked by the
+// validator of h, see [WithValidator].
+func (h *hooks[K, V]) validated(fn func(context.Context) (V, error)) func(context.Context) (V, error) {
+	if h.validate == nil {
+		return fn
+	}
+	return func(ctx context.Context) (V, error) {
+		return h.validation(fn(ctx))
+	}
+}
+
+// validation returns the result of a function which returned value and err,
+// failing with the error of the validator of h if it rejects value.
+func (h *hooks[K, V]) validation(value V, err error) (V, error) {
+	if err != nil || h.validate == nil {
+		return value, err
+	}
+	if err := h.validate(value); err != nil {
+		var zero V
+		return zero, err
+	}
+	return value, nil
+}
+
+// wrap wraps fn, executed for key, with the behaviors of the group around the
+// executions of functions: the panic handler, the validator, the execution
+// keys and the concurrency limit of the group. The validator is left out if
+// validated reports that fn validates its values itself.
+func (g *Group[K, V]) wrap(key K, fn func(context.Context) (V, error), validated bool) func(context.Context) (V, error) {
+	fn = g.hooks.serialized(key, limit(&g.opts, fn))
+	if !validated {
+		fn = g.hooks.validated(fn)
+	}
+	return guard(&g.hooks, key, fn)
+}
+
+// limit wraps fn so that it holds a slot of the group's concurrency limit
+// while executing, see [WithMaxConcurrency].
+func limit[V any](o *options, fn func(context.Context) (V, error)) func(context.Context) (V, error) {
+	if o.sem == nil {
+		return fn
+	}
+	return func(ctx context.Context) (V, error) {
+		select {
+		case o.sem <- struct{}{}:
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+		defer func() { <-o.sem }()
+		return fn(ctx)
+	}
+}
+
+// sharedErr returns the error err of a call to return to a caller which did
+// not execute its function, see [WithErrorWrap].
+func (o *options) sharedErr(err error, waiters int32) error {
+	if err == nil || err == ErrFlushed || !o.errorWrap {
+		return err
+	}
+	return &SharedError{Err: err, Waiters: waiters}
+}
+
+// cacheTTL returns how long a result whose error is err must be cached,
+// jittered according to [WithTTLJitter].
+func (o *options) cacheTTL(err error) time.Duration {
+	switch {
+	case err == nil:
+		return o.jittered(o.ttl)
+	case transient(err):
+		return 0
+	default:
+		return o.jittered(o.errTTL)
+	}
+}
+
+// transient reports whether err is specific to the callers of a call or to
+// a single execution of its function, so that it must never be shared with
+// subsequent callers: a context error or a [*PanicError].
+func transient(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, new(*PanicError))
+}
+
+// jittered returns d randomized within ±[WithTTLJitter] of its value.
+func (o *options) jittered(d time.Duration) time.Duration {
+	if o.jitter == 0 || d <= 0 {
+		return d
+	}
+	return d + time.Duration((2*rand.Float64()-1)*o.jitter*float64(d))
+}
+
+// callOptions holds the settings of a single call to a [Group].
+type callOptions struct {
+	memoize   bool          // see [Group.DoOnce].
+	noWait    bool          // see [Group.TryDo].
+	skipCache bool          // see [SkipCache].
+	noJoin    bool          // see [NoJoin].
+	timeout   time.Duration // see [WaitTimeout].
+	waiters   *int32        // see [Group.DoCount], set before the function executes.
+	delegated bool          // whether the leader is not a caller, see [WithBackgroundLeader].
+	priority  int           // see [Group.DoPriority].
+	ctxless   bool          // whether the function ignores its context, see [Group.lead].
+	validated bool          // whether the function validates its values, see [Group.DoLocked].
+}
+
+// hooks holds the generic options of a [Group], resolved from its [options]
+// against the type parameters of the group.
+type hooks[K comparable, V any] struct {
+	observer  Observer[K, V]
+	evict     func(key K, value V, reason EvictReason)
+	clone     func(V) V
+	admit     func(key K, value V) bool
+	panic     func(key K, recovered any, stack []byte)
+	fallback  func(key K, err error) (V, bool)
+	keyString func(key K) string
+	validate  func(V) error
+
+	executionKey func(key K) any
+	locks        *keyLocks // locks of the execution keys, see [WithExecutionKey].
+}
+
+// newHooks resolves the generic options of o for a Group[K, V].
+func newHooks[K comparable, V any](o *options) hooks[K, V] {
+	return hooks[K, V]{
+		observer:  typedOption[Observer[K, V]]("WithObserver", o.observer),
+		evict:     typedOption[func(K, V, EvictReason)]("WithEvictionCallback", o.evictionCallback),
+		clone:     typedOption[func(V) V]("WithValueCloner", o.valueCloner),
+		admit:     typedOption[func(K, V) bool]("WithAdmission", o.admission),
+		panic:     typedOption[func(K, any, []byte)]("WithPanicHandler", o.panicHandler),
+		fallback:  typedOption[func(K, error) (V, bool)]("WithFallback", o.fallback),
+		keyString: typedOption[func(K) string]("WithKeyStringer", o.keyStringer),
+		validate:  typedOption[func(V) error]("WithValidator", o.validator),
+
+		executionKey: typedOption[func(K) any]("WithExecutionKey", o.executionKey),
+		locks:        new(keyLocks),
+	}
+}
+
+// share returns the value of a call to hand to a caller which did not execute
+// its function, see [WithValueCloner].
+func (h *hooks[K, V]) share(value V) V {
+	if h.clone != nil {
+		return h.clone(value)
+	}
+	return value
+}
+
+// typedOption returns v, the value given to the generic option name, as a T.
+// It panics if the option was given for a group with other type parameters.
+func typedOption[T any](name string, v any) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	t, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("inflight: %s option of type %T given to a group expecting %v", name, v, reflect.TypeFor[T]()))
+	}
+	return t
+}
@@ -0,0 +1,41 @@
+package inflight
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy configures the retries of [Group.DoWithRetry].
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of executions of the function,
+	// including the first one. A value <= 1 disables retries.
+	MaxAttempts int
+	// Backoff returns how long to wait before the next attempt, given the
+	// number of failed attempts so far (starting at 1). A nil Backoff retries
+	// immediately.
+	Backoff func(attempt int) time.Duration
+	// IsRetryable reports whether a failed attempt should be retried given
+	// its error. A nil IsRetryable retries every error.
+	IsRetryable func(err error) bool
+}
+
+// DoWithRetry is like [Group.Do], but the leader retries fn on transient
+// failures according to policy. The retries happen within the single
+// execution of the leader, so waiters never see intermediate failures, only
+// the final value or error.
+//
+// DoWithRetry is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) DoWithRetry(key K, fn func() (V, error), policy RetryPolicy) (V, bool, error) {
+	value, info, err := g.do(context.Background(), key, func(context.Context) (V, error) {
+		for attempt := 1; ; attempt++ {
+			value, err := fn()
+			if err == nil || attempt >= policy.MaxAttempts || (policy.IsRetryable != nil && !policy.IsRetryable(err)) {
+				return value, err
+			}
+			if policy.Backoff != nil {
+				time.Sleep(policy.Backoff(attempt))
+			}
+		}
+	}, callOptions{ctxless: true})
+	return value, info.Shared, err
+}
@@ -0,0 +1,65 @@
+package inflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	var g Group[string, string]
+	transient := errors.New("transient error")
+
+	var attempts atomic.Int32
+	fn := func() (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		if attempts.Add(1) <= 2 {
+			return "", transient
+		}
+		return "bar", nil
+	}
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     func(attempt int) time.Duration { return time.Duration(attempt) * time.Millisecond },
+		IsRetryable: func(err error) bool { return errors.Is(err, transient) },
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			v, _, err := g.DoWithRetry("key", fn, policy)
+			require.NoError(t, err)
+			require.Equal(t, "bar", v)
+		})
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(3), attempts.Load())
+	require.Equal(t, int64(1), g.Stats().Executions)
+}
+
+func TestDoWithRetryExhausted(t *testing.T) {
+	var g Group[string, string]
+	someErr := errors.New("some error")
+
+	var attempts atomic.Int32
+	_, _, err := g.DoWithRetry("key", func() (string, error) {
+		attempts.Add(1)
+		return "", someErr
+	}, RetryPolicy{MaxAttempts: 3})
+	require.ErrorIs(t, err, someErr)
+	require.Equal(t, int32(3), attempts.Load())
+
+	// Non retryable errors are returned right away.
+	attempts.Store(0)
+	_, _, err = g.DoWithRetry("key", func() (string, error) {
+		attempts.Add(1)
+		return "", someErr
+	}, RetryPolicy{MaxAttempts: 3, IsRetryable: func(error) bool { return false }})
+	require.ErrorIs(t, err, someErr)
+	require.Equal(t, int32(1), attempts.Load())
+}
@@ -0,0 +1,76 @@
+package inflight
+
+import (
+	"context"
+	"hash/maphash"
+	"runtime"
+)
+
+// ShardedGroup is a [Group] whose keys are spread across several independent
+// groups, called shards, by a hash of the key, to reduce the contention on a
+// single map under very high throughput. The calls for a given key always go
+// to the same shard, so the semantics for a key are identical to the ones of
+// a Group.
+//
+// ShardedGroup is safe for concurrent use by multiple goroutines.
+type ShardedGroup[K comparable, V any] struct {
+	seed      maphash.Seed
+	shards    []*Group[K, V]
+	normalize func(K) K // see [WithKeyNormalizer].
+}
+
+// NewSharded creates a new [ShardedGroup] with n shards, each of them created
+// with [New] and the given options. A value of n <= 0 defaults to
+// [runtime.GOMAXPROCS].
+//
+// The limit set by [WithMaxConcurrency] applies to the ShardedGroup as a
+// whole: its shards share their slots. With [WithJanitor], every shard runs
+// its own janitor, until [ShardedGroup.Close] is called.
+func NewSharded[K comparable, V any](n int, opts ...Option) *ShardedGroup[K, V] {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	s := &ShardedGroup[K, V]{seed: maphash.MakeSeed(), shards: make([]*Group[K, V], n)}
+	s.shards[0] = New[K, V](opts...)
+	for i := 1; i < n; i++ {
+		// Share the options, and so the semaphore, of the first shard.
+		s.shards[i] = &Group[K, V]{opts: s.shards[0].opts}
+		s.shards[i].init()
+	}
+	s.normalize = typedOption[func(K) K]("WithKeyNormalizer", s.shards[0].opts.keyNormalizer)
+	return s
+}
+
+// shard returns the shard of key, picked from its normalized form so that
+// the keys normalized to the same value share their shard.
+func (s *ShardedGroup[K, V]) shard(key K) *Group[K, V] {
+	if s.normalize != nil {
+		key = s.normalize(key)
+	}
+	return s.shards[maphash.Comparable(s.seed, key)%uint64(len(s.shards))]
+}
+
+// Do is like [Group.Do].
+func (s *ShardedGroup[K, V]) Do(key K, fn func() (V, error)) (V, bool, error) {
+	return s.shard(key).Do(key, fn)
+}
+
+// DoContext is like [Group.DoContext].
+func (s *ShardedGroup[K, V]) DoContext(ctx context.Context, key K, fn func(context.Context) (V, error)) (V, bool, error) {
+	return s.shard(key).DoContext(ctx, key, fn)
+}
+
+// Forget is like [Group.Forget].
+func (s *ShardedGroup[K, V]) Forget(key K) {
+	s.shard(key).Forget(key)
+}
+
+// Close closes every shard, see [Group.Close].
+//
+// Close is safe for concurrent use by multiple goroutines.
+func (s *ShardedGroup[K, V]) Close() error {
+	for _, g := range s.shards {
+		g.Close()
+	}
+	return nil
+}
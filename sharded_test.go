@@ -0,0 +1,144 @@
+package inflight
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedGroup(t *testing.T) {
+	g := NewSharded[string, string](4, WithTTL(time.Minute))
+
+	const keys = 32
+	var calls [keys]atomic.Int32
+	var wg sync.WaitGroup
+	for i := range keys {
+		for range 4 {
+			wg.Go(func() {
+				key := strconv.Itoa(i)
+				v, _, err := g.Do(key, func() (string, error) {
+					calls[i].Add(1)
+					time.Sleep(10 * time.Millisecond)
+					return key, nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, key, v)
+			})
+		}
+	}
+	wg.Wait()
+	for i := range keys {
+		require.Equal(t, int32(1), calls[i].Load())
+	}
+
+	g.Forget("3")
+	_, shared, err := g.Do("3", func() (string, error) {
+		calls[3].Add(1)
+		return "3", nil
+	})
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, int32(2), calls[3].Load())
+}
+
+func TestShardedGroupKeyNormalizer(t *testing.T) {
+	g := NewSharded[string, string](16, WithKeyNormalizer(normalizeKey), WithTTL(time.Minute))
+
+	var calls atomic.Int32
+	for i := range 64 {
+		key := strings.Repeat(" ", i%4) + strings.ToUpper("foo")[:i%4] + "foo"[i%4:]
+		v, _, err := g.Do(key, func() (string, error) {
+			calls.Add(1)
+			return "bar", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "bar", v)
+	}
+	// Every form of the key was served by the same shard.
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestShardedGroupMaxConcurrency(t *testing.T) {
+	const n = 3
+	g := NewSharded[int, int](8, WithMaxConcurrency(n))
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			key := i % 10
+			v, _, err := g.Do(key, func() (int, error) {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := maxRunning.Load()
+					if cur <= old || maxRunning.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return key, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, key, v)
+		})
+	}
+	wg.Wait()
+	// The limit applies across the shards.
+	require.LessOrEqual(t, maxRunning.Load(), int32(n))
+	require.Positive(t, maxRunning.Load())
+}
+
+func TestShardedGroupClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	g := NewSharded[string, string](4, WithTTL(time.Minute), WithJanitor(time.Millisecond))
+	require.Equal(t, before+4, runtime.NumGoroutine())
+
+	require.NoError(t, g.Close())
+	require.NoError(t, g.Close())
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		require.True(t, time.Now().Before(deadline), "janitors still running")
+		time.Sleep(time.Millisecond)
+	}
+	_, _, err := g.Do("key", func() (string, error) { return "bar", nil })
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestNewShardedDefault(t *testing.T) {
+	g := NewSharded[string, string](0)
+	require.NotEmpty(t, g.shards)
+}
+
+func BenchmarkShardedGroup(b *testing.B) {
+	keys := make([]int, 4096)
+	for i := range keys {
+		keys[i] = i
+	}
+	fn := func() (int, error) { return 0, nil }
+
+	single := New[int, int]()
+	sharded := NewSharded[int, int](0)
+	for _, bc := range []struct {
+		name string
+		do   func(key int, fn func() (int, error)) (int, bool, error)
+	}{
+		{"Single", single.Do},
+		{"Sharded", sharded.Do},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					bc.do(keys[i%len(keys)], fn)
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package inflight
+
+import (
+	"context"
+	"time"
+)
+
+// WithStaleWhileRevalidate caches successful results for hard once their call
+// completes, like [WithTTL], but considers them stale after soft, with
+// soft < hard.
+//
+// A caller getting a stale result gets it immediately with shared=true, and
+// triggers a refresh of the key in the background: its function is executed
+// again with the values of its context, but without its cancellation (see
+// [context.WithoutCancel]). A single refresh runs at a time for a key, the
+// other callers keep getting the stale result until it completes. The fresh
+// result then replaces the stale one, unless the refresh failed, in which
+// case the stale result is kept until the next caller triggers another
+// refresh, or until it expires. Once a result expires after hard, callers
+// block on a new execution as usual.
+//
+// A panic of a function refreshing a key is not recovered, since nobody waits
+// on it, unless [WithPanicToError] is given.
+//
+// Results cached forever, see [Group.DoOnce], never become stale.
+func WithStaleWhileRevalidate(soft, hard time.Duration) Option {
+	return func(o *options) { o.ttl, o.staleAfter = hard, soft }
+}
+
+// stale reports whether the cached result of call is stale, see
+// [WithStaleWhileRevalidate].
+func (g *Group[K, V]) stale(call *call[V]) bool {
+	return g.opts.staleAfter > 0 && call.err == nil && call.cached() && call.expires != noExpiry &&
+		g.opts.now().Sub(call.at) >= g.opts.staleAfter
+}
+
+// revalidate executes fn in the background to refresh the stale cached result
+// of key held by call, unless a refresh is already running.
+func (g *Group[K, V]) revalidate(ctx context.Context, key K, stale *call[V], fn func(context.Context) (V, error)) {
+	if !stale.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	g.running.start()
+	go func() {
+		defer g.running.stop()
+		g.refresh(context.WithoutCancel(ctx), key, stale, fn)
+	}()
+}
+
+// refresh executes fn with ctx, then replaces the stale call of key with its
+// result if it succeeded.
+func (g *Group[K, V]) refresh(ctx context.Context, key K, stale *call[V], fn func(context.Context) (V, error)) {
+	g.stats.executions.Add(1)
+	if d := g.opts.callTimeout; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	done := g.hooks.onStart(ctx, key)
+	start := time.Now()
+	call := newCall[V](&g.opts)
+	call.leave()
+	execute(ctx, &g.opts, g.wrap(key, fn, false), func(v V, err error, p any) {
+		call.elapsed = time.Since(start)
+		g.stats.failed(err, p)
+		if p != nil {
+			g.hooks.onPanicked(done, p, call.elapsed)
+		} else {
+			g.hooks.onFinish(done, key, false, err, call.elapsed)
+		}
+		if err != nil || p != nil || g.closed.Load() {
+			stale.refreshing.Store(false)
+			return
+		}
+		g.hooks.admission(key, call, v, nil)
+		cached, _ := call.complete(v, nil, nil)
+		call.publish()
+		if !cached {
+			stale.refreshing.Store(false)
+			return
+		}
+		if g.store().CompareAndSwap(key, stale, call) {
+			g.evicted(key, stale, Replaced)
+			g.cache(key, call)
+		}
+	})
+}
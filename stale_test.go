@@ -0,0 +1,92 @@
+package inflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	const soft, hard = 30 * time.Millisecond, 90 * time.Millisecond
+	g := New[string, int](WithStaleWhileRevalidate(soft, hard))
+
+	var calls atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		n := calls.Add(1)
+		if n == 2 {
+			refreshed <- struct{}{}
+			<-release
+		}
+		return int(n), nil
+	}
+	v, shared, err := g.Do("key", fn)
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, 1, v)
+
+	// Fresh: the cached result is served without refreshing it.
+	v, shared, err = g.Do("key", fn)
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, 1, v)
+	require.Equal(t, int32(1), calls.Load())
+
+	// Stale: the cached result is served immediately, while a single
+	// refresh runs in the background.
+	time.Sleep(soft)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			v, shared, err := g.Do("key", fn)
+			require.NoError(t, err)
+			require.True(t, shared)
+			require.Equal(t, 1, v)
+		})
+	}
+	wg.Wait()
+	<-refreshed
+	require.Equal(t, int32(2), calls.Load())
+	close(release)
+	g.Wait()
+
+	// The refreshed result replaced the stale one.
+	v, shared, err = g.Do("key", fn)
+	require.NoError(t, err)
+	require.True(t, shared)
+	require.Equal(t, 2, v)
+	require.Equal(t, int32(2), calls.Load())
+
+	// Expired: callers block on a new execution.
+	time.Sleep(hard)
+	v, shared, err = g.Do("key", fn)
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, 3, v)
+}
+
+func TestWithStaleWhileRevalidateError(t *testing.T) {
+	const soft, hard = 10 * time.Millisecond, time.Minute
+	g := New[string, int](WithStaleWhileRevalidate(soft, hard))
+	mustDoInt := func(fn func() (int, error), want int) {
+		t.Helper()
+		v, _, err := g.Do("key", fn)
+		require.NoError(t, err)
+		require.Equal(t, want, v)
+	}
+	mustDoInt(func() (int, error) { return 1, nil }, 1)
+	time.Sleep(soft)
+
+	// A failed refresh keeps the stale result, and the next caller triggers
+	// another refresh.
+	mustDoInt(func() (int, error) { return 0, errors.New("some error") }, 1)
+	g.Wait()
+	mustDoInt(func() (int, error) { return 2, nil }, 1)
+	g.Wait()
+	mustDoInt(func() (int, error) { return 3, nil }, 2)
+}
@@ -0,0 +1,102 @@
+package inflight
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of the cumulative counters of a [Group], see
+// [Group.Stats]. Callers can diff two snapshots to compute rates.
+type Stats struct {
+	Calls      int64 // number of calls made to the group, one per key for [Group.DoMulti].
+	Executions int64 // number of executions of a function.
+	Errors     int64 // number of executions of a function which returned an error or panicked.
+	Shared     int64 // number of calls whose result was shared with other callers.
+	Forgets    int64 // number of calls to [Group.Forget].
+	// Orphans is the number of calls whose waiters all stopped waiting
+	// before they completed, such as callers of [Group.DoContext] whose
+	// context is done, leaving their leader executing the function for
+	// itself. A growing count hints at functions outliving the callers
+	// interested in their result, which should honor their context.
+	Orphans int64
+}
+
+// stats holds the counters of a [Group].
+type stats struct {
+	calls      atomic.Int64
+	executions atomic.Int64
+	errors     atomic.Int64
+	shared     atomic.Int64
+	forgets    atomic.Int64
+	orphans    atomic.Int64
+}
+
+// failed counts an execution of a function as failed if it returned err != nil
+// or panicked with p != nil.
+func (s *stats) failed(err error, p any) {
+	if err != nil || p != nil {
+		s.errors.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the cumulative counters of the group.
+//
+// The Shared counter is incremented once for every call which returned
+// shared=true, so it is consistent with the results of [Group.Do].
+//
+// Stats is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Stats() Stats {
+	return Stats{
+		Calls:      g.stats.calls.Load(),
+		Executions: g.stats.executions.Load(),
+		Errors:     g.stats.errors.Load(),
+		Shared:     g.stats.shared.Load(),
+		Forgets:    g.stats.forgets.Load(),
+		Orphans:    g.stats.orphans.Load(),
+	}
+}
+
+// KeyStat is the number of callers waiting on the call in flight for a key,
+// see [Group.TopKeys].
+type KeyStat[K comparable] struct {
+	Key     K
+	Waiters int32 // number of callers waiting on the call, besides its leader.
+}
+
+// TopKeys returns up to n keys having a call in flight, with the highest
+// numbers of waiters first, to diagnose hot keys. Keys with a cached result
+// and no call in flight are ignored.
+//
+// The result is a point-in-time snapshot of the group, which may already be
+// stale when TopKeys returns.
+//
+// TopKeys is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) TopKeys(n int) []KeyStat[K] {
+	var top []KeyStat[K]
+	for key, call := range g.store().Range {
+		if !call.completed() {
+			top = append(top, KeyStat[K]{Key: key, Waiters: max(call.callers.Load()-1, 0)})
+		}
+	}
+	slices.SortStableFunc(top, func(a, b KeyStat[K]) int {
+		return cmp.Compare(b.Waiters, a.Waiters)
+	})
+	return top[:max(min(n, len(top)), 0)]
+}
+
+// Waiters returns the number of callers executing or waiting on the call in
+// flight for key, including its leader, or 0 if key has no call in flight.
+// This is useful for adaptive load shedding, such as serving a degraded
+// response once too many callers wait on a key.
+//
+// The result may already be stale when Waiters returns.
+//
+// Waiters is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Waiters(key K) int32 {
+	call, ok := g.store().Load(key)
+	if !ok || call.completed() {
+		return 0
+	}
+	return call.callers.Load()
+}
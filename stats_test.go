@@ -0,0 +1,180 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	var g Group[string, string]
+	require.Equal(t, Stats{}, g.Stats())
+
+	const n = 16
+	block := make(chan struct{})
+	var nbShared atomic.Int64
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, shared, err := g.Do("key", func() (string, error) {
+				<-block
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			if shared {
+				nbShared.Add(1)
+			}
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+	close(block)
+	wg.Wait()
+
+	g.Do("other", func() (string, error) { return "", nil })
+	g.Do("failing", func() (string, error) { return "", errors.New("some error") })
+	g.Forget("key")
+
+	require.Equal(t, Stats{
+		Calls:      n + 2,
+		Executions: 3,
+		Errors:     1,
+		Shared:     nbShared.Load(),
+		Forgets:    1,
+	}, g.Stats())
+	require.Equal(t, int64(n), nbShared.Load())
+}
+
+func TestStatsOrphans(t *testing.T) {
+	var g Group[string, string]
+	block := make(chan struct{})
+	var started, wg sync.WaitGroup
+	started.Add(1)
+	wg.Go(func() {
+		v, shared, err := g.Do("key", func() (string, error) {
+			started.Done()
+			<-block
+			return "bar", nil
+		})
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.Equal(t, "bar", v)
+	})
+	started.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	const n = 4
+	var waiters sync.WaitGroup
+	for range n {
+		waiters.Go(func() {
+			_, _, err := g.DoContext(ctx, "key", func(context.Context) (string, error) { return "", nil })
+			require.ErrorIs(t, err, context.Canceled)
+		})
+	}
+	require.Eventually(t, func() bool { return g.Waiters("key") == n+1 }, time.Second, time.Millisecond)
+	cancel()
+	waiters.Wait()
+	require.Zero(t, g.Stats().Orphans)
+
+	// The leader finishes for itself.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int64(1), g.Stats().Orphans)
+
+	// A call without waiters is not orphaned.
+	g.Do("other", func() (string, error) { return "", nil })
+	require.Equal(t, int64(1), g.Stats().Orphans)
+}
+
+func TestStatsOrphansPromotion(t *testing.T) {
+	var g Group[string, string]
+
+	leaderCtx, cancelLeader := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		_, _, err := g.DoContext(leaderCtx, "key", func(ctx context.Context) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	}()
+	<-started
+	waiterDone := make(chan struct{})
+	go func() {
+		defer close(waiterDone)
+		v, _, err := g.DoContext(t.Context(), "key", func(context.Context) (string, error) { return "promoted", nil })
+		require.NoError(t, err)
+		require.Equal(t, "promoted", v)
+	}()
+	require.Eventually(t, func() bool { return g.Waiters("key") == 2 }, time.Second, time.Millisecond)
+
+	// The promoted waiter receives the result of the call.
+	cancelLeader()
+	<-leaderDone
+	<-waiterDone
+	require.Zero(t, g.Stats().Orphans)
+}
+
+func TestTopKeys(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	g.Set("cached", "bar")
+	require.Empty(t, g.TopKeys(10))
+
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for key, n := range map[string]int{"a": 2, "b": 5, "c": 1, "d": 3} {
+		for range n {
+			wg.Go(func() {
+				g.Do(key, func() (string, error) {
+					<-block
+					return "bar", nil
+				})
+			})
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+
+	require.Equal(t, []KeyStat[string]{
+		{Key: "b", Waiters: 4},
+		{Key: "d", Waiters: 2},
+		{Key: "a", Waiters: 1},
+	}, g.TopKeys(3))
+	require.Len(t, g.TopKeys(10), 4)
+
+	close(block)
+	wg.Wait()
+	require.Empty(t, g.TopKeys(10))
+}
+
+func TestWaiters(t *testing.T) {
+	g := NewWithTTL[string, string](time.Minute)
+	require.Zero(t, g.Waiters("key"))
+
+	const n = 5
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			g.Do("key", func() (string, error) {
+				<-block
+				return "bar", nil
+			})
+		})
+	}
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+	require.Equal(t, int32(n), g.Waiters("key"))
+	require.Zero(t, g.Waiters("other"))
+
+	// A cached result has no waiters.
+	close(block)
+	wg.Wait()
+	require.Zero(t, g.Waiters("key"))
+}
@@ -0,0 +1,95 @@
+package inflight
+
+import "github.com/go4org/hashtriemap"
+
+// Store is a concurrent map of opaque entries, which can back a [Group]
+// instead of the default implementation, see [NewWithStore]. Its method set
+// mirrors the one of [sync.Map], keyed by K.
+//
+// Entries must be compared by identity: CompareAndSwap and CompareAndDelete
+// compare them with ==. A [Group] only removes the entry of a call through
+// CompareAndDelete once the call completes, so that it never removes another
+// call stored under the same key in the meantime, for instance after
+// [Group.Forget].
+//
+// A Store must be safe for concurrent use by multiple goroutines.
+type Store[K comparable] interface {
+	Load(key K) (value any, ok bool)
+	LoadOrStore(key K, value any) (actual any, loaded bool)
+	LoadAndDelete(key K) (value any, loaded bool)
+	Swap(key K, value any) (previous any, loaded bool)
+	CompareAndSwap(key K, old, new any) (swapped bool)
+	CompareAndDelete(key K, old any) (deleted bool)
+	Range(yield func(key K, value any) bool)
+}
+
+// store is the typed counterpart of [Store], used internally by [Group].
+// It is implemented by [hashtriemap.HashTrieMap], the default store.
+type store[K comparable, V any] interface {
+	Load(key K) (value V, ok bool)
+	LoadOrStore(key K, value V) (actual V, loaded bool)
+	LoadAndDelete(key K) (value V, loaded bool)
+	Swap(key K, value V) (previous V, loaded bool)
+	CompareAndSwap(key K, old, new V) (swapped bool)
+	CompareAndDelete(key K, old V) (deleted bool)
+	Range(yield func(key K, value V) bool)
+}
+
+var _ store[string, *call[int]] = (*hashtriemap.HashTrieMap[string, *call[int]])(nil)
+
+// anyStore adapts a user provided [Store] to a [store].
+type anyStore[K comparable, V any] struct {
+	s Store[K]
+}
+
+// typed returns v as a V, v being nil if it was not found in the store.
+func typed[V any](v any, ok bool) (V, bool) {
+	t, _ := v.(V)
+	return t, ok
+}
+
+func (a anyStore[K, V]) Load(key K) (V, bool) { return typed[V](a.s.Load(key)) }
+
+func (a anyStore[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	return typed[V](a.s.LoadOrStore(key, value))
+}
+
+func (a anyStore[K, V]) LoadAndDelete(key K) (V, bool) {
+	return typed[V](a.s.LoadAndDelete(key))
+}
+
+func (a anyStore[K, V]) Swap(key K, value V) (V, bool) {
+	return typed[V](a.s.Swap(key, value))
+}
+
+func (a anyStore[K, V]) CompareAndSwap(key K, old, new V) bool {
+	return a.s.CompareAndSwap(key, old, new)
+}
+
+func (a anyStore[K, V]) CompareAndDelete(key K, old V) bool {
+	return a.s.CompareAndDelete(key, old)
+}
+
+func (a anyStore[K, V]) Range(yield func(K, V) bool) {
+	a.s.Range(func(key K, value any) bool {
+		v, _ := value.(V)
+		return yield(key, v)
+	})
+}
+
+// NewWithStore creates a new [Group] backed by s instead of the default
+// store, configured with the given options. It is meant for advanced users,
+// for example to benchmark alternative concurrent maps.
+//
+// s must be empty, and must not be used by anything else than the group.
+func NewWithStore[K comparable, V any](s Store[K], opts ...Option) *Group[K, V] {
+	return newGroup[K, V](anyStore[K, *call[V]]{s: s}, opts)
+}
+
+// store returns the store backing the group.
+func (g *Group[K, V]) store() store[K, *call[V]] {
+	if g.custom != nil {
+		return g.custom
+	}
+	return &g.m
+}
@@ -0,0 +1,128 @@
+package inflight
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syncMapStore is a [Store] backed by a [sync.Map].
+type syncMapStore[K comparable] struct {
+	m sync.Map
+}
+
+func (s *syncMapStore[K]) Load(key K) (any, bool) { return s.m.Load(key) }
+
+func (s *syncMapStore[K]) LoadOrStore(key K, value any) (any, bool) {
+	return s.m.LoadOrStore(key, value)
+}
+
+func (s *syncMapStore[K]) LoadAndDelete(key K) (any, bool) { return s.m.LoadAndDelete(key) }
+
+func (s *syncMapStore[K]) Swap(key K, value any) (any, bool) { return s.m.Swap(key, value) }
+
+func (s *syncMapStore[K]) CompareAndSwap(key K, old, new any) bool {
+	return s.m.CompareAndSwap(key, old, new)
+}
+
+func (s *syncMapStore[K]) CompareAndDelete(key K, old any) bool {
+	return s.m.CompareAndDelete(key, old)
+}
+
+func (s *syncMapStore[K]) Range(yield func(K, any) bool) {
+	s.m.Range(func(key, value any) bool { return yield(key.(K), value) })
+}
+
+func TestNewWithStore(t *testing.T) {
+	var s syncMapStore[string]
+	g := NewWithStore[string, string](&s, WithTTL(time.Minute))
+
+	const n = 16
+	var calls atomic.Int32
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, _, err := g.Do("key", func() (string, error) {
+				calls.Add(1)
+				<-block
+				return "bar", nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "bar", v)
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond) // Ensure all goroutines are waiting.
+	_, ok := s.m.Load("key")
+	require.True(t, ok)
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+
+	// The result is cached in the store, until forgotten.
+	require.Equal(t, 1, g.Len())
+	g.Forget("key")
+	_, ok = s.m.Load("key")
+	require.False(t, ok)
+}
+
+func TestForgetThenNewCall(t *testing.T) {
+	for name, g := range map[string]*Group[string, string]{
+		"default": New[string, string](),
+		"store":   NewWithStore[string, string](new(syncMapStore[string])),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The first call is forgotten while in flight.
+			releaseFirst := make(chan struct{})
+			firstDone := make(chan struct{})
+			go func() {
+				defer close(firstDone)
+				v, _, err := g.Do("key", func() (string, error) {
+					<-releaseFirst
+					return "first", nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, "first", v)
+			}()
+			require.Eventually(t, func() bool { return g.InFlight("key") }, time.Second, time.Millisecond)
+			g.Forget("key")
+
+			// A new call is stored under the same key.
+			releaseSecond := make(chan struct{})
+			secondDone := make(chan struct{})
+			go func() {
+				defer close(secondDone)
+				v, _, err := g.Do("key", func() (string, error) {
+					<-releaseSecond
+					return "second", nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, "second", v)
+			}()
+			require.Eventually(t, func() bool { return g.InFlight("key") }, time.Second, time.Millisecond)
+
+			// The completion of the first call leaves the new one in place,
+			// new callers keep joining it.
+			close(releaseFirst)
+			<-firstDone
+			require.True(t, g.InFlight("key"))
+			require.Equal(t, 1, g.Len())
+			joined := make(chan string)
+			go func() {
+				v, shared, err := g.Do("key", func() (string, error) { return "third", nil })
+				require.NoError(t, err)
+				require.True(t, shared)
+				joined <- v
+			}()
+			time.Sleep(10 * time.Millisecond) // Ensure the caller joined.
+			close(releaseSecond)
+			<-secondDone
+			require.Equal(t, "second", <-joined)
+			require.Zero(t, g.Len())
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package inflight
+
+import "sync"
+
+// Synchronizer holds the leader of every call of a group until a given
+// number of waiters joined the call, before it executes its function. It is
+// meant for tests, to deterministically exercise deduplication without
+// sleeping, see [WithSynchronizer].
+//
+// A Synchronizer can be shared by several groups.
+type Synchronizer struct {
+	waiters int32
+
+	mu   sync.Mutex
+	cond sync.Cond // broadcast when a waiter joins a call.
+}
+
+// NewSynchronizer creates a new [Synchronizer] holding the leaders until
+// waiters callers joined their call.
+func NewSynchronizer(waiters int) *Synchronizer {
+	s := &Synchronizer{waiters: int32(waiters)}
+	s.cond.L = &s.mu
+	return s
+}
+
+// WithSynchronizer makes the leaders of the calls of the group wait for the
+// waiters expected by s before executing their function. This is meant for
+// tests only: a leader waits forever if not enough callers join its call,
+// regardless of its context.
+func WithSynchronizer(s *Synchronizer) Option {
+	return func(o *options) { o.sync = s }
+}
+
+// joined notifies the leaders held by s that a waiter joined their call.
+// It is a no-op on a nil Synchronizer.
+func (s *Synchronizer) joined() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// await blocks until callers reports enough waiters besides the leader.
+// It is a no-op on a nil Synchronizer.
+func (s *Synchronizer) await(callers func() int32) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for callers()-1 < s.waiters {
+		s.cond.Wait()
+	}
+}
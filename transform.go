@@ -0,0 +1,23 @@
+package inflight
+
+// DoTransform is like [Group.Do], but each caller gets its own view of the
+// shared result: fn is deduplicated as usual, then every caller applies its
+// own transform to the value it returned, such as a projection of the fields
+// it needs. transform is not called if fn returns an error, in which case
+// DoTransform returns the zero value of W.
+//
+// DoTransform is a function rather than a method, since methods can't have
+// their own type parameters.
+//
+// The value passed to transform is shared by all the callers, and possibly
+// cached: transform must not mutate it, see [WithValueCloner].
+//
+// DoTransform is safe for concurrent use by multiple goroutines.
+func DoTransform[K comparable, V, W any](g *Group[K, V], key K, fn func() (V, error), transform func(V) W) (W, bool, error) {
+	value, shared, err := g.Do(key, fn)
+	if err != nil {
+		var zero W
+		return zero, shared, err
+	}
+	return transform(value), shared, nil
+}
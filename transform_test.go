@@ -0,0 +1,58 @@
+package inflight
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestDoTransform(t *testing.T) {
+	var g Group[string, user]
+
+	var calls atomic.Int32
+	block := make(chan struct{})
+	fn := func() (user, error) {
+		calls.Add(1)
+		<-block
+		return user{ID: 42, Name: "gopher"}, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		name, shared, err := DoTransform(&g, "key", fn, func(u user) string { return u.Name })
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "gopher", name)
+	})
+	wg.Go(func() {
+		id, shared, err := DoTransform(&g, "key", fn, func(u user) string { return strconv.Itoa(u.ID) })
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.Equal(t, "42", id)
+	})
+	time.Sleep(10 * time.Millisecond) // Ensure both callers joined.
+	close(block)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestDoTransformError(t *testing.T) {
+	var g Group[string, user]
+	someErr := errors.New("some error")
+
+	name, _, err := DoTransform(&g, "key", func() (user, error) { return user{}, someErr }, func(user) string {
+		panic("unreachable")
+	})
+	require.ErrorIs(t, err, someErr)
+	require.Empty(t, name)
+}
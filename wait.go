@@ -0,0 +1,67 @@
+package inflight
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// running counts the functions being executed by a group, see [Group.Wait].
+// Unlike a [sync.WaitGroup], it can be incremented concurrently with waiting.
+// The executions only update an atomic counter: the lock is only taken by the
+// last one to stop while someone waits.
+type running struct {
+	n       atomic.Int64
+	waiting atomic.Int32 // number of goroutines in [running.wait].
+
+	mu   sync.Mutex
+	idle chan struct{} // closed once n drops to zero, nil if nobody waits.
+}
+
+// start counts a new execution, which must be followed by a call to
+// [running.stop] once it returns.
+func (r *running) start() {
+	r.n.Add(1)
+}
+
+// stop uncounts an execution, and releases the waiters if it was the last one.
+func (r *running) stop() {
+	if r.n.Add(-1) != 0 || r.waiting.Load() == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n.Load() == 0 && r.idle != nil {
+		close(r.idle)
+		r.idle = nil
+	}
+}
+
+// wait blocks until no execution is running.
+func (r *running) wait() {
+	r.waiting.Add(1)
+	defer r.waiting.Add(-1)
+	r.mu.Lock()
+	if r.n.Load() == 0 {
+		r.mu.Unlock()
+		return
+	}
+	if r.idle == nil {
+		r.idle = make(chan struct{})
+	}
+	idle := r.idle
+	r.mu.Unlock()
+	<-idle
+}
+
+// Wait blocks until no function is being executed by the group, including
+// the functions of flushed calls (see [Group.Flush]). Combined with
+// [Group.Close], it ensures that all the leaders finished during a graceful
+// shutdown.
+//
+// Without calling Close first, Wait may block indefinitely if new calls keep
+// arriving, and it may return right before a new call starts.
+//
+// Wait is safe for concurrent use by multiple goroutines.
+func (g *Group[K, V]) Wait() {
+	g.running.wait()
+}